@@ -0,0 +1,36 @@
+// Copyright 2024, Edoardo Putti
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestReadDotenv(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"A=1 # comment", []string{"A=1"}},
+		{"A=1\t# comment", []string{"A=1"}},
+		{"A=a#b", []string{"A=a#b"}},
+		{"A= # comment", []string{"A="}},
+		{"A=#comment", []string{"A="}},
+		{"A=", []string{"A="}},
+		{`A="hello # world"`, []string{"A=hello # world"}},
+		{`A="hello # world" # comment`, []string{"A=hello # world"}},
+		{"A='a # b' # comment", []string{"A=a # b"}},
+		{`A="a\nb"`, []string{"A=a\nb"}},
+		{"# A=1\n\nB=2", []string{"B=2"}},
+	}
+	for _, tt := range tests {
+		got, err := readDotenv(strings.NewReader(tt.in))
+		if err != nil || !slices.Equal(got, tt.want) {
+			t.Errorf("readDotenv(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
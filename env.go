@@ -8,32 +8,62 @@
 package env
 
 import (
+	"cmp"
 	"encoding"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"maps"
+	"math"
+	"net/netip"
+	"net/url"
 	"os"
+	"path"
 	"reflect"
+	"regexp"
+	"runtime"
 	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+	"text/template"
 	"time"
+	"unicode"
 )
 
 // ErrHelp is the error returned if the HELP or H environment variable is set
 // but no such variable is defined.
 var ErrHelp = errors.New("env: help requested")
 
+// ErrSealed is the error returned when a sealed set would read the
+// process environment, see [EnvSet.SetSealed].
+var ErrSealed = errors.New("env: sealed EnvSet requires explicit input")
+
+// ErrRedefined is the error returned by [EnvSet.TryVar] when a variable
+// with the same name is defined already.
+var ErrRedefined = errors.New("variable redefined")
+
 // errParse is returned by Set if a variable's value fails to parse,
 // such as with an invalid integer for Int.
-// It then gets wrapped through failf to provide more information.
+// It then gets wrapped in a ParseError to provide more information.
 var errParse = errors.New("parse error")
 
 // errRange is returned by Set if a variable's value is out of range.
-// It then gets wrapped through failf to provide more information.
+// It then gets wrapped in a ParseError to provide more information.
 var errRange = errors.New("value out of range")
 
+// boundsError is returned by Set if a variable's value is out of the bounds
+// given at definition. Its message includes the bounds and it matches errRange.
+type boundsError struct{ msg string }
+
+func (b *boundsError) Error() string { return b.msg }
+
+func (b *boundsError) Unwrap() error { return errRange }
+
 func numError(err error) error {
 	ne, ok := err.(*strconv.NumError)
 	if !ok {
@@ -69,6 +99,8 @@ func (b *boolValue) Get() any { return bool(*b) }
 
 func (b *boolValue) String() string { return strconv.FormatBool(bool(*b)) }
 
+func (b *boolValue) clone() Value { return cloned(b) }
+
 func (b *boolValue) IsBoolVar() bool { return true }
 
 type boolVar interface {
@@ -97,6 +129,35 @@ func (b *intValue) Get() any { return int(*b) }
 
 func (b *intValue) String() string { return strconv.Itoa(int(*b)) }
 
+func (b *intValue) clone() Value { return cloned(b) }
+
+// -- countValue
+type countValue int
+
+func newCountValue(val int, p *int) *countValue {
+	*p = val
+	return (*countValue)(p)
+}
+
+func (c *countValue) Set(s string) error {
+	if s == "" {
+		*c++
+		return nil
+	}
+	v, err := strconv.ParseInt(s, 0, strconv.IntSize)
+	if err != nil {
+		return numError(err)
+	}
+	*c = countValue(v)
+	return nil
+}
+
+func (c *countValue) Get() any { return int(*c) }
+
+func (c *countValue) String() string { return strconv.Itoa(int(*c)) }
+
+func (c *countValue) clone() Value { return cloned(c) }
+
 // -- int64Value
 type int64Value int64
 
@@ -118,6 +179,8 @@ func (i *int64Value) Get() any { return int64(*i) }
 
 func (i *int64Value) String() string { return strconv.FormatInt(int64(*i), 10) }
 
+func (i *int64Value) clone() Value { return cloned(i) }
+
 // -- uintValue
 type uintValue uint
 
@@ -139,6 +202,8 @@ func (u *uintValue) Get() any { return uint(*u) }
 
 func (u *uintValue) String() string { return strconv.FormatUint(uint64(*u), 10) }
 
+func (u *uintValue) clone() Value { return cloned(u) }
+
 // -- uint64Value
 type uint64Value uint64
 
@@ -160,6 +225,80 @@ func (u *uint64Value) Get() any { return uint64(*u) }
 
 func (u *uint64Value) String() string { return strconv.FormatUint(uint64(*u), 10) }
 
+func (u *uint64Value) clone() Value { return cloned(u) }
+
+// -- bitmaskValue
+type bitmaskValue struct {
+	p     *uint64
+	names map[string]uint64
+}
+
+func newBitmaskValue(val uint64, p *uint64, names map[string]uint64) (*bitmaskValue, error) {
+	var all uint64
+	for _, bits := range names {
+		all |= bits
+	}
+	if val&^all != 0 {
+		return nil, fmt.Errorf("default %#x has bits not named", val)
+	}
+	*p = val
+	return &bitmaskValue{p: p, names: names}, nil
+}
+
+func (b *bitmaskValue) Set(s string) error {
+	var v uint64
+	for _, name := range strings.Split(s, "|") {
+		name = strings.TrimSpace(name)
+		if bits, err := strconv.ParseUint(name, 0, 64); err == nil {
+			v |= bits
+			continue
+		}
+		bits, ok := b.names[name]
+		if !ok {
+			names := slices.Sorted(maps.Keys(b.names))
+			return fmt.Errorf("%w: unknown flag %q, not one of %s", errParse, name, strings.Join(names, ", "))
+		}
+		v |= bits
+	}
+	*b.p = v
+	return nil
+}
+
+func (b *bitmaskValue) Get() any { return *b.p }
+
+func (b *bitmaskValue) String() string {
+	if b.p == nil || *b.p == 0 {
+		return "0"
+	}
+	// name the bits in increasing order, skipping the names
+	// whose bits are all named already.
+	names := slices.SortedFunc(maps.Keys(b.names), func(x, y string) int {
+		if c := cmp.Compare(b.names[x], b.names[y]); c != 0 {
+			return c
+		}
+		return strings.Compare(x, y)
+	})
+	var set []string
+	var named uint64
+	for _, name := range names {
+		bits := b.names[name]
+		if bits != 0 && bits&*b.p == bits && bits&^named != 0 {
+			set = append(set, name)
+			named |= bits
+		}
+	}
+	if rest := *b.p &^ named; rest != 0 {
+		set = append(set, fmt.Sprintf("%#x", rest))
+	}
+	return strings.Join(set, "|")
+}
+
+func (b *bitmaskValue) clone() Value {
+	c := *b
+	c.p = cloned(b.p)
+	return &c
+}
+
 // -- stringValue
 type stringValue string
 
@@ -177,6 +316,34 @@ func (s *stringValue) Get() any { return string(*s) }
 
 func (s *stringValue) String() string { return string(*s) }
 
+func (s *stringValue) clone() Value { return cloned(s) }
+
+// -- logFormatValue
+type logFormatValue LogFormat
+
+func newLogFormatValue(val LogFormat, p *LogFormat) *logFormatValue {
+	*p = val
+	return (*logFormatValue)(p)
+}
+
+func (f *logFormatValue) Set(s string) error {
+	for _, name := range logFormats {
+		if strings.EqualFold(name, s) {
+			*f = logFormatValue(name)
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: not one of %s", errParse, strings.Join(logFormats, ", "))
+}
+
+func (f *logFormatValue) Get() any { return LogFormat(*f) }
+
+func (f *logFormatValue) String() string { return string(*f) }
+
+func (f *logFormatValue) clone() Value { return cloned(f) }
+
+func (f *logFormatValue) choices() []string { return logFormats }
+
 // -- float64Value
 type float64Value float64
 
@@ -198,6 +365,8 @@ func (s *float64Value) Get() any { return float64(*s) }
 
 func (s *float64Value) String() string { return strconv.FormatFloat(float64(*s), 'g', -1, 64) }
 
+func (s *float64Value) clone() Value { return cloned(s) }
+
 // -- durationValue
 type durationValue time.Duration
 
@@ -219,717 +388,5593 @@ func (d *durationValue) Get() any { return time.Duration(*d) }
 
 func (d *durationValue) String() string { return time.Duration(*d).String() }
 
-// -- textValue
-type textValue struct{ p encoding.TextUnmarshaler }
+func (d *durationValue) clone() Value { return cloned(d) }
 
-func newTextValue(val encoding.TextUnmarshaler, p encoding.TextUnmarshaler) textValue {
-	ptrVal := reflect.ValueOf(p)
-	if ptrVal.Kind() != reflect.Ptr {
-		panic("variable value type must be a pointer")
-	}
-	defVal := reflect.ValueOf(val)
-	if defVal.Kind() != reflect.Ptr {
-		defVal = defVal.Elem()
-	}
-	if defVal.Type() != ptrVal.Type().Elem() {
-		panic(fmt.Sprintf("default type value does not match variable type: %v != %v", defVal.Type(), ptrVal.Type().Elem()))
-	}
-	ptrVal.Elem().Set(defVal)
-	return textValue{p}
+// -- namedDurationValue
+type namedDurationValue struct {
+	p       *time.Duration
+	presets map[string]time.Duration
 }
 
-func (v textValue) Set(s string) error {
-	return v.p.UnmarshalText([]byte(s))
+func newNamedDurationValue(val time.Duration, p *time.Duration, presets map[string]time.Duration) *namedDurationValue {
+	*p = val
+	return &namedDurationValue{p: p, presets: presets}
 }
 
-func (v textValue) Get() any {
-	return v.p
+func (d *namedDurationValue) Set(s string) error {
+	for name, v := range d.presets {
+		if strings.EqualFold(name, s) {
+			*d.p = v
+			return nil
+		}
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		names := slices.Sorted(maps.Keys(d.presets))
+		return fmt.Errorf("%w: not a duration nor one of %s", errParse, strings.Join(names, ", "))
+	}
+	*d.p = v
+	return nil
 }
 
-func (v textValue) String() string {
-	if m, ok := v.p.(encoding.TextMarshaler); ok {
-		if b, err := m.MarshalText(); err == nil {
-			return string(b)
+func (d *namedDurationValue) Get() any { return *d.p }
+
+func (d *namedDurationValue) String() string {
+	if d.p == nil {
+		return time.Duration(0).String()
+	}
+	for _, name := range slices.Sorted(maps.Keys(d.presets)) {
+		if d.presets[name] == *d.p {
+			return name
 		}
 	}
-	return ""
+	return d.p.String()
 }
 
-// -- funcValue
-type funcValue func(string) error
+func (d *namedDurationValue) clone() Value {
+	c := *d
+	c.p = cloned(d.p)
+	return &c
+}
 
-func (f funcValue) Set(s string) error { return f(s) }
+// -- durationRangeValue
+type durationRangeValue struct {
+	p        *time.Duration
+	min, max time.Duration
+}
 
-func (f funcValue) String() string { return "" }
+func newDurationRangeValue(val time.Duration, p *time.Duration, min, max time.Duration) (*durationRangeValue, error) {
+	d := &durationRangeValue{p: p, min: min, max: max}
+	if err := d.check(val); err != nil {
+		return nil, fmt.Errorf("default %v", err)
+	}
+	*p = val
+	return d, nil
+}
 
-func (f funcValue) Get() any {
+func (d *durationRangeValue) check(v time.Duration) error {
+	if v < d.min || v > d.max {
+		return &boundsError{fmt.Sprintf("%v out of range [%v,%v]", v, d.min, d.max)}
+	}
 	return nil
 }
 
-// -- boolFuncValue
-type boolFuncValue func(string) error
+func (d *durationRangeValue) Set(s string) error {
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return errParse
+	}
+	if err := d.check(v); err != nil {
+		return err
+	}
+	*d.p = v
+	return nil
+}
 
-func (f boolFuncValue) Set(s string) error { return f(s) }
+func (d *durationRangeValue) Get() any { return *d.p }
 
-func (f boolFuncValue) String() string { return "" }
+func (d *durationRangeValue) String() string {
+	if d.p == nil {
+		return time.Duration(0).String()
+	}
+	return d.p.String()
+}
 
-func (f boolFuncValue) Get() any { return nil }
+func (d *durationRangeValue) clone() Value {
+	c := *d
+	c.p = cloned(d.p)
+	return &c
+}
 
-// Value is the interface to the dynamic value stored in a Spec.
-// (The default value is represented as a string.)
-//
-// Set is called once, in declaration order, for each variable present.
-// The env package may call the [String] method with a zero-valued receiver,
-// such as a nil pointer.
-//
-// Get allows the contents of Value to be retrieved.
-type Value interface {
-	String() string
-	Set(string) error
-	Get() any
+// -- scheduleValue
+type scheduleValue []time.Duration
+
+func newScheduleValue(val []time.Duration, p *[]time.Duration) *scheduleValue {
+	*p = val
+	return (*scheduleValue)(p)
 }
 
-// ErrorHandling defines how [EnvSet.Parse] behaves if the parse fails.
-type ErrorHandling int
+func (v *scheduleValue) Set(s string) error {
+	var schedule []time.Duration
+	if s != "" {
+		var last time.Duration
+		for _, tok := range strings.Split(s, ",") {
+			inc, isInc := strings.CutPrefix(strings.TrimSpace(tok), "+")
+			d, err := time.ParseDuration(inc)
+			if err != nil {
+				return fmt.Errorf("%w: invalid duration %q", errParse, tok)
+			}
+			if isInc {
+				d += last
+			}
+			schedule = append(schedule, d)
+			last = d
+		}
+	}
+	*v = schedule
+	return nil
+}
 
-// These constants cause [EnvSet.Parse] to behave as described if the parse fails.
-const (
-	ContinueOnError ErrorHandling = iota // returns a descriptive error
-	ExitOnError                          // Call os.Exit(2)
-	PanicOnError                         // Call panic with a descriptive error
-)
+func (v *scheduleValue) Get() any { return []time.Duration(*v) }
 
-// A EnvSet represents a set of defined environment variables. The zero value of a EnvSet
-// has no name.
-//
-// [Var] name must be unique within an EnvSet. An attempt to define a variable whose
-// name is already in use will cause a panic.
-type EnvSet struct {
-	// Usage is the function called when an error occur while parsing environment variables.
-	// The field is a function (not a method) that may be changed to point to a
-	// custom error handler. What happens after Usage is called depends on the
-	// ErrorHandling setting; this defaults to ExitOnError, which exits the program
-	// after calling Usage.
-	Usage func()
+func (v *scheduleValue) String() string {
+	var b strings.Builder
+	for i, d := range *v {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		if i > 0 && d >= (*v)[i-1] {
+			b.WriteByte('+')
+			d -= (*v)[i-1]
+		}
+		b.WriteString(d.String())
+	}
+	return b.String()
+}
+
+func (v *scheduleValue) clone() Value { return cloned(v) }
 
-	name          string
-	parsed        bool
-	actual        map[string]*Spec
-	formal        map[string]*Spec
-	environment   []string
-	errorHandling ErrorHandling
-	output        io.Writer         // nil means stderr; use Output() accessor
-	undef         map[string]string // variables which didn't exists at the time of set
+// -- signedDurationValue
+type signedDurationValue struct {
+	p      *time.Duration
+	policy SignPolicy
 }
 
-// A Spec represents the state of an environment variable.
-type Spec struct {
-	Name        string // name as it appears in environment
-	Description string // short description
-	Value       Value  // value as set
-	DefValue    string // default value (as text); for description message
+func newSignedDurationValue(val time.Duration, p *time.Duration, policy SignPolicy) (*signedDurationValue, error) {
+	d := &signedDurationValue{p: p, policy: policy}
+	if err := d.check(val); err != nil {
+		return nil, fmt.Errorf("default %v", err)
+	}
+	*p = val
+	return d, nil
 }
 
-// sortVariables returns the variables as a slice in lexicographical sorted order.
-func sortVariables(vars map[string]*Spec) []*Spec {
-	result := make([]*Spec, len(vars))
-	i := 0
-	for _, s := range vars {
-		result[i] = s
-		i++
+func (d *signedDurationValue) check(v time.Duration) error {
+	switch {
+	case d.policy == RequirePositive && v <= 0:
+		return &boundsError{fmt.Sprintf("%v is not positive", v)}
+	case d.policy == RequireNonNegative && v < 0:
+		return &boundsError{fmt.Sprintf("%v is negative", v)}
 	}
-	slices.SortFunc(result, func(a, b *Spec) int {
-		return strings.Compare(a.Name, b.Name)
-	})
-	return result
+	return nil
 }
 
-// Output returns the destination for description and errro messages. [os.Stderr] is returned if
-// output was not set or was set to nil.
-func (e *EnvSet) Output() io.Writer {
-	if e.output == nil {
-		return os.Stderr
+func (d *signedDurationValue) Set(s string) error {
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return errParse
 	}
-	return e.output
+	if err := d.check(v); err != nil {
+		return err
+	}
+	*d.p = v
+	return nil
 }
 
-// Name returns the name of the environment set.
-func (e *EnvSet) Name() string {
-	return e.name
+func (d *signedDurationValue) Get() any { return *d.p }
+
+func (d *signedDurationValue) String() string {
+	if d.p == nil {
+		return time.Duration(0).String()
+	}
+	return d.p.String()
 }
 
-// ErrorHandling returns the error handling behavior of the variable set.
-func (e *EnvSet) ErrorHandling() ErrorHandling {
-	return e.errorHandling
+func (d *signedDurationValue) clone() Value {
+	c := *d
+	c.p = cloned(d.p)
+	return &c
 }
 
-// SetOutput sets the destination for usage and error messages.
-// If output is nil, [os.Stderr] is used.
-func (e *EnvSet) SetOutput(output io.Writer) {
-	e.output = output
+// -- percentValue
+type percentValue float64
+
+func newPercentValue(val float64, p *float64) *percentValue {
+	*p = val
+	return (*percentValue)(p)
 }
 
-// VisitAll visits the variables in lexicographical order, calling fn for each.
-// It visits all, even those not set.
-func (e *EnvSet) VisitAll(fn func(*Spec)) {
-	for _, spec := range sortVariables(e.formal) {
-		fn(spec)
+func (f *percentValue) Set(s string) error {
+	num, isPercent := strings.CutSuffix(s, "%")
+	v, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return numError(err)
+	}
+	if isPercent {
+		if isDecimal(num) {
+			// move the point rather than divide by 100, which rounds
+			// twice: every percentage String returns parses back exactly.
+			v, _ = strconv.ParseFloat(movePoint(num, -2), 64)
+		} else {
+			v /= 100
+		}
+	}
+	if !(v >= 0 && v <= 1) { // NaN is out of range too
+		return errRange
 	}
+	*f = percentValue(v)
+	return nil
 }
 
-// VisitAll visits the variables in lexicographical order, calling
-// fn for each. It visits all, even those not set.
-func VisitAll(fn func(*Spec)) {
-	Environment.VisitAll(fn)
-}
+func (f *percentValue) Get() any { return float64(*f) }
 
-// Visit visits the variables in lexicographical order, calling fn for each.
-// It visits only those that have been set.
-func (e *EnvSet) Visit(fn func(*Spec)) {
-	for _, spec := range sortVariables(e.actual) {
-		fn(spec)
+func (f *percentValue) String() string {
+	// move the point of the shortest decimal for the value, as
+	// multiplying by 100 can add digits: 0.07*100 is 7.000000000000001.
+	s := strconv.FormatFloat(float64(*f), 'f', -1, 64)
+	if !isDecimal(s) {
+		return s
 	}
+	return movePoint(s, 2) + "%"
 }
 
-// Visit visits the variables in lexicographical order, calling fn
-// for each. It visits only those that have been set.
-func Visit(fn func(*Spec)) {
-	Environment.Visit(fn)
+func (f *percentValue) clone() Value { return cloned(f) }
+
+// isDecimal reports whether s is made of digits and at most one decimal point.
+func isDecimal(s string) bool {
+	return s != "" && strings.Trim(s, "0123456789.") == "" && strings.Count(s, ".") <= 1
 }
 
-// isZeroValue determines whether the string represents the zero
-// value for a variable.
-func isZeroValue(spec *Spec, value string) (ok bool, err error) {
-	// Build a zero value of the variable's Value type, and see if the
-	// result of calling its String method equals the value passed in.
-	// This works unless the Value type is itself an interface type.
-	typ := reflect.TypeOf(spec.Value)
-	var z reflect.Value
-	if typ.Kind() == reflect.Pointer {
-		z = reflect.New(typ.Elem())
-	} else {
-		z = reflect.Zero(typ)
+// movePoint moves the decimal point of the decimal s by n places, to the
+// right if n is positive, dropping leading and trailing zeros.
+func movePoint(s string, n int) string {
+	whole, frac, _ := strings.Cut(s, ".")
+	digits := whole + frac
+	point := len(whole) + n
+	if point < 0 {
+		digits = strings.Repeat("0", -point) + digits
+		point = 0
 	}
-	// Catch panics calling the String method, which shouldn't prevent the
-	// usage message from being printed, but that we should report to the
-	// user so that they know to fix their code.
-	defer func() {
-		if e := recover(); e != nil {
-			if typ.Kind() == reflect.Pointer {
-				typ = typ.Elem()
-			}
-			err = fmt.Errorf("panic calling String method on zero %v for variable %s: %v", typ, spec.Name, e)
-		}
-	}()
-	return value == z.Interface().(Value).String(), nil
-}
-
-// UnquoteUsage extracts a back-quoted name from the usage
-// string for an environment variable and returns it and the un-quoted usage.
-// Given "a `name` to show" it returns ("name", "a name to show").
-// If there are no back quotes, the name is an educated guess of the
-// type of the variables's value.
-func UnquoteUsage(spec *Spec) (name string, description string) {
-	// Look for a back-quoted name, but avoid the strings package.
-	description = spec.Description
-	for i := 0; i < len(description); i++ {
-		if description[i] == '`' {
-			for j := i + 1; j < len(description); j++ {
-				if description[j] == '`' {
-					name = description[i+1 : j]
-					description = description[:i] + name + description[j+1:]
-					return name, description
-				}
-			}
-			break // Only one back quote; use type name.
-		}
+	if point > len(digits) {
+		digits += strings.Repeat("0", point-len(digits))
 	}
-	// No explicit name, so use type if we can find one.
-	name = "value"
-	switch spec.Value.(type) {
-	case *boolValue:
-		name = "boolean"
-	case *durationValue:
-		name = "duration"
-	case *float64Value:
-		name = "float"
-	case *intValue, *int64Value:
-		name = "int"
-	case *stringValue:
-		name = "string"
-	case *uintValue, *uint64Value:
-		name = "uint"
+	whole, frac = strings.TrimLeft(digits[:point], "0"), strings.TrimRight(digits[point:], "0")
+	if whole == "" {
+		whole = "0"
 	}
-	return
+	if frac == "" {
+		return whole
+	}
+	return whole + "." + frac
 }
 
-// PrintDefaults print, to standard error unless configured otherwise, the
-// default values of all defined environment variables in the set. See the
-// documentation for the global function PrintDefaults for more information.
-func (e *EnvSet) PrintDefaults() {
-	var isZeroValueErrs []error
-	e.VisitAll(func(spec *Spec) {
-		var b strings.Builder
-		fmt.Fprintf(&b, "  %s", spec.Name)
-		name, usage := UnquoteUsage(spec)
-		if len(name) > 0 {
-			b.WriteString("  ")
-			b.WriteString(name)
-		}
-		b.WriteString("\n    \t")
-		b.WriteString(strings.ReplaceAll(usage, "\n", "\n    \t"))
-		// Print the default value only if it differs to the zero value
-		// for this variable type.
-		if isZero, err := isZeroValue(spec, spec.DefValue); err != nil {
-			isZeroValueErrs = append(isZeroValueErrs, err)
-		} else if !isZero {
-			if _, ok := spec.Value.(*stringValue); ok {
-				// put quotes on the value
-				fmt.Fprintf(&b, " (default %q)", spec.DefValue)
-			} else {
-				fmt.Fprintf(&b, " (default %v)", spec.DefValue)
-			}
-		}
-		fmt.Fprint(e.Output(), b.String(), "\n")
-	})
-	// if calling string on any zero env.values triggered a panic, print
-	// the messages after the full set of defaults so that the programmer
-	// knows to fix the panic.
-	if errs := isZeroValueErrs; len(errs) > 0 {
-		fmt.Fprintln(e.Output())
-		for _, err := range errs {
-			fmt.Fprintln(e.Output(), err)
-		}
+// -- basisPointsValue
+type basisPointsValue struct {
+	p        *int
+	min, max int
+}
+
+func newBasisPointsValue(val int, p *int, min, max int) (*basisPointsValue, error) {
+	if val < min || val > max {
+		return nil, fmt.Errorf("default %d out of range [%d,%d]", val, min, max)
 	}
+	*p = val
+	return &basisPointsValue{p: p, min: min, max: max}, nil
 }
 
-// PrintDefaults print, to standard error, unless configured otherwise, the
-// default values of all defined environment variables.
-// For an integer valued variable x, the default output has the form
-//
-// x  int
-//
-//	description-message-for-x (default: 7)
-//
-// The description message will appear on a separate line.
-// The parenthetical default is omitted if the default is the zero value for
-// the type. The listed type, here int, can be changed by placing a back-quoted
-// name in the variable's description string; the first such item in the message is taken
-// to be a parameter name to show in the message and the back quotes are
-// stripped from the message when displayed. For instance, given
-//
-//	env.String("I", "", "search `directory` for include files")
-//
-// the output will be
-//
-// I  directory
-//
-//	search directory for include files.
-//
-// To change the destination for variable messages, call [Environment].SetOutput.
-func PrintDefaults() {
-	Environment.PrintDefaults()
+func (b *basisPointsValue) Set(s string) error {
+	v, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+	if err != nil {
+		return numError(err)
+	}
+	if math.IsNaN(v) {
+		return errParse
+	}
+	bp := math.Round(v * 100)
+	if bp < float64(b.min) || bp > float64(b.max) {
+		return &boundsError{fmt.Sprintf("%s out of range [%s,%s]", s, formatBasisPoints(b.min), formatBasisPoints(b.max))}
+	}
+	*b.p = int(bp)
+	return nil
 }
 
-// defaultEnvironment is the default function to print a usage message.
-func (e *EnvSet) defaultEnvironment() {
-	if e.name == "" {
-		fmt.Fprintf(e.Output(), "Environment:\n")
-	} else {
-		fmt.Fprintf(e.Output(), "Environment of %s:\n", e.name)
+func (b *basisPointsValue) Get() any { return *b.p }
+
+func (b *basisPointsValue) String() string {
+	if b.p == nil {
+		return formatBasisPoints(0)
 	}
-	e.PrintDefaults()
+	return formatBasisPoints(*b.p)
 }
 
-// BoolVar defines a bool environment variable with specified name, default value, and description string.
-// The argument p points to a bool variable in which to store the value of the
-// environment variable.
-func (e *EnvSet) BoolVar(p *bool, name string, value bool, description string) {
-	e.Var(newBoolValue(value, p), name, description)
+func (b *basisPointsValue) clone() Value {
+	c := *b
+	c.p = cloned(b.p)
+	return &c
 }
 
-// BoolVar defines a bool environment variable with specified name, default value, and description string.
-// The argument p points to a bool variable in which to store the value of
-// the environment variable.
-func BoolVar(p *bool, name string, value bool, description string) {
-	Environment.Var(newBoolValue(value, p), name, description)
+// formatBasisPoints formats bp basis points as a percentage.
+func formatBasisPoints(bp int) string {
+	return strconv.FormatFloat(float64(bp)/100, 'f', -1, 64) + "%"
 }
 
-// Bool defines a bool environment variable with specified name, default value, and description string.
-// The return value is the address of a bool variable that stores the value of
-// the environment variable.
-func (e *EnvSet) Bool(name string, value bool, description string) *bool {
-	p := new(bool)
-	e.Var(newBoolValue(value, p), name, description)
-	return p
+// -- rolloutValue
+type rolloutValue float64
+
+func newRolloutValue(val float64, p *float64) *rolloutValue {
+	*p = val
+	return (*rolloutValue)(p)
 }
 
-// Bool defines a bool environment variable with specified name, default value, and description string.
-// The return value is the address of a bool variable that stores the value of the environment variable.
-func Bool(name string, value bool, description string) *bool {
-	return Environment.Bool(name, value, description)
+func (r *rolloutValue) Set(s string) error {
+	if b, err := strconv.ParseBool(boolWord(s)); err == nil {
+		if b {
+			*r = 1
+		} else {
+			*r = 0
+		}
+		return nil
+	}
+	return (*percentValue)(r).Set(s)
 }
 
-// IntVar defines an int environment variable with specified name, default value, and description string.
-// The argument p points to an int variable in which to store the value of the environment variable.
-func (e *EnvSet) IntVar(p *int, name string, value int, description string) {
-	e.Var(newIntValue(value, p), name, description)
+func (r *rolloutValue) Get() any { return float64(*r) }
+
+func (r *rolloutValue) String() string {
+	switch *r {
+	case 0:
+		return "off"
+	case 1:
+		return "on"
+	}
+	return (*percentValue)(r).String()
 }
 
-// IntVar defines an int environment variable with specified name, default value, and description string.
-// The argument p points to an int variable in which to store the value of the variable.
-func IntVar(p *int, name string, value int, description string) {
-	Environment.Var(newIntValue(value, p), name, description)
+func (r *rolloutValue) clone() Value { return cloned(r) }
+
+// -- unitValue
+type unitValue struct {
+	p     *float64
+	units map[string]float64
 }
 
-// Int defines an int environment variable with specified name, default value, and description string.
-// The return value is the address of an int variable that stores the value of the variable.
-func (e *EnvSet) Int(name string, value int, description string) *int {
-	p := new(int)
-	e.Var(newIntValue(value, p), name, description)
-	return p
+func newUnitValue(val float64, p *float64, units map[string]float64) *unitValue {
+	*p = val
+	return &unitValue{p: p, units: units}
 }
 
-// Int defines an int environment variable with specified name, default value, and description string.
-// The return value is the address of an int variable that stores the value of the variable.
-func Int(name string, value int, description string) *int {
-	return Environment.Int(name, value, description)
+func (u *unitValue) Set(s string) error {
+	// try the longest units first, so that "ms" is not taken for "s".
+	names := slices.SortedFunc(maps.Keys(u.units), func(a, b string) int {
+		return cmp.Or(cmp.Compare(len(b), len(a)), strings.Compare(a, b))
+	})
+	for _, name := range names {
+		num, ok := strings.CutSuffix(s, name)
+		if !ok {
+			continue
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(num), 64)
+		if err != nil {
+			continue
+		}
+		*u.p = v * u.units[name]
+		return nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		slices.Sort(names)
+		return fmt.Errorf("%w: not a number followed by one of %s", errParse, strings.Join(names, ", "))
+	}
+	*u.p = v
+	return nil
 }
 
-// Int64Var defines an int64 environment variable with specified name, default value, and description string.
-// The argument p points to an int64 variable in which to store the value of the variable.
-func (e *EnvSet) Int64Var(p *int64, name string, value int64, description string) {
-	e.Var(newInt64Value(value, p), name, description)
+func (u *unitValue) Get() any { return *u.p }
+
+// IsZero implements ZeroValuer, as the zero unitValue has no units to format.
+func (u *unitValue) IsZero() bool { return *u.p == 0 }
+
+func (u *unitValue) String() string {
+	var v float64
+	if u.p != nil {
+		v = *u.p
+	}
+	s := strconv.FormatFloat(v, 'g', -1, 64)
+	// name the base unit, if any
+	for _, name := range slices.Sorted(maps.Keys(u.units)) {
+		if u.units[name] == 1 {
+			return s + name
+		}
+	}
+	return s
 }
 
-// Int64Var defines an int64 environment variable with specified name, default value, and description string.
-// The argument p points to an int64 variable in which to store the value of the variable.
-func Int64Var(p *int64, name string, value int64, description string) {
-	Environment.Var(newInt64Value(value, p), name, description)
+func (u *unitValue) clone() Value {
+	c := *u
+	c.p = cloned(u.p)
+	return &c
 }
 
-// Int64 defines an int64 environment variable with specified name, default value, and description string.
-// The return value is the address of an int64 variable that stores the value of the variable.
-func (e *EnvSet) Int64(name string, value int64, description string) *int64 {
-	p := new(int64)
-	e.Var(newInt64Value(value, p), name, description)
-	return p
+// -- jsonSliceValue
+type jsonSliceValue[T any] []T
+
+func newJSONSliceValue[T any](val []T, p *[]T) *jsonSliceValue[T] {
+	*p = val
+	return (*jsonSliceValue[T])(p)
 }
 
-// Int64 defines an int64 environment variable with specified name, default value, and description string.
-// The return value is the address of an int64 variable that stores the value of the variable.
-func Int64(name string, value int64, description string) *int64 {
-	return Environment.Int64(name, value, description)
+func (v *jsonSliceValue[T]) Set(s string) error {
+	var elems []T
+	if err := json.Unmarshal([]byte(s), &elems); err != nil {
+		return err
+	}
+	*v = jsonSliceValue[T](elems)
+	return nil
 }
 
-// UintVar defines a uint environment variable with specified name, default value, and description string.
-// The argument p points to a uint variable in which to store the value of the variable.
-func (e *EnvSet) UintVar(p *uint, name string, value uint, description string) {
-	e.Var(newUintValue(value, p), name, description)
+func (v *jsonSliceValue[T]) Get() any { return []T(*v) }
+
+func (v *jsonSliceValue[T]) String() string {
+	if len(*v) == 0 {
+		return "[]"
+	}
+	b, err := json.Marshal([]T(*v))
+	if err != nil {
+		return ""
+	}
+	return string(b)
 }
 
-// UintVar defines a uint environment variable with specified name, default value, and description string.
-// The argument p points to a uint variable in which to store the value of the variable.
-func UintVar(p *uint, name string, value uint, description string) {
-	Environment.Var(newUintValue(value, p), name, description)
+func (v *jsonSliceValue[T]) clone() Value { return cloned(v) }
+
+func (v *jsonSliceValue[T]) typeName() string { return "json" }
+
+// -- unixTimeValue
+type unixTimeValue struct {
+	p     *time.Time
+	milli bool // epoch is in milliseconds rather than seconds
 }
 
-// Uint defines a uint environment variable with specified name, default value, and description string.
-// The return value is the address of a uint variable that stores the value of the variable.
-func (e *EnvSet) Uint(name string, value uint, description string) *uint {
-	p := new(uint)
-	e.Var(newUintValue(value, p), name, description)
-	return p
+func newUnixTimeValue(val time.Time, p *time.Time, milli bool) *unixTimeValue {
+	*p = val
+	return &unixTimeValue{p: p, milli: milli}
 }
 
-// Uint defines a uint environment variable with specified name, default value, and description string.
-// The return value is the address of a uint variable that stores the value of the variable.
-func Uint(name string, value uint, description string) *uint {
-	return Environment.Uint(name, value, description)
+func (u *unixTimeValue) Set(s string) error {
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return numError(err)
+	}
+	if u.milli {
+		*u.p = time.UnixMilli(v)
+	} else {
+		*u.p = time.Unix(v, 0)
+	}
+	return nil
 }
 
-// Uint64Var defines a uint64 environment variable with specified name, default value, and description string.
-// The argument p points to a uint64 variable in which to store the value of the variable.
-func (e *EnvSet) Uint64Var(p *uint64, name string, value uint64, description string) {
-	e.Var(newUint64Value(value, p), name, description)
+func (u *unixTimeValue) Get() any { return *u.p }
+
+func (u *unixTimeValue) String() string {
+	if u.p == nil {
+		return "0"
+	}
+	if u.milli {
+		return strconv.FormatInt(u.p.UnixMilli(), 10)
+	}
+	return strconv.FormatInt(u.p.Unix(), 10)
 }
 
-// Uint64Var defines a uint64 environment variable with specified name, default value, and description string.
-// The argument p points to a uint64 variable in which to store the value of the variable.
-func Uint64Var(p *uint64, name string, value uint64, description string) {
-	Environment.Var(newUint64Value(value, p), name, description)
+func (u *unixTimeValue) clone() Value {
+	c := *u
+	c.p = cloned(u.p)
+	return &c
 }
 
-// Uint64 defines a uint64 environment variable with specified name, default value, and description string.
-// The return value is the address of a uint64 variable that stores the value of the variable.
-func (e *EnvSet) Uint64(name string, value uint64, description string) *uint64 {
-	p := new(uint64)
-	e.Var(newUint64Value(value, p), name, description)
-	return p
+// -- timeValue
+type timeValue struct {
+	p      *time.Time
+	layout string
 }
 
-// Uint64 defines a uint64 environment variable with specified name, default value, and description string.
-// The return value is the address of a uint64 variable that stores the value of the variable.
-func Uint64(name string, value uint64, description string) *uint64 {
-	return Environment.Uint64(name, value, description)
+func newTimeValue(val time.Time, p *time.Time, layout string) *timeValue {
+	*p = val
+	return &timeValue{p: p, layout: timeLayout(layout)}
 }
 
-// StringVar defines a string environment variable with specified name, default value, and description string.
+// timeLayout returns the layout used for times, RFC 3339 if layout is empty.
+func timeLayout(layout string) string {
+	if layout == "" {
+		return time.RFC3339
+	}
+	return layout
+}
+
+func (t *timeValue) Set(s string) error {
+	v, err := time.Parse(t.layout, s)
+	if err != nil {
+		return fmt.Errorf("%w: not a time in the layout %s", errParse, t.layout)
+	}
+	*t.p = v
+	return nil
+}
+
+func (t *timeValue) Get() any { return *t.p }
+
+func (t *timeValue) String() string {
+	if t.p == nil {
+		return ""
+	}
+	return t.p.Format(t.layout)
+}
+
+func (t *timeValue) clone() Value {
+	c := *t
+	c.p = cloned(t.p)
+	return &c
+}
+
+func (t *timeValue) IsZero() bool { return t.p == nil || t.p.IsZero() }
+
+// -- sliceValue
+type sliceValue[T any] struct {
+	p     *[]T
+	sep   string
+	parse func(string) (T, error)
+	first bool // stop at the first invalid element
+}
+
+func newSliceValue[T any](val []T, p *[]T, sep string, parse func(string) (T, error)) *sliceValue[T] {
+	if sep == "" {
+		panic("empty list separator")
+	}
+	*p = val
+	return &sliceValue[T]{p: p, sep: sep, parse: parse}
+}
+
+func (v *sliceValue[T]) Set(s string) error {
+	elems := []T{}
+	if s == "" {
+		*v.p = elems
+		return nil
+	}
+	var errs []error
+	for i, tok := range splitEscaped(s, v.sep) {
+		elem, err := v.parse(tok)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("element %d: %w", i+1, err))
+			if v.first {
+				break
+			}
+			continue
+		}
+		elems = append(elems, elem)
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	*v.p = elems
+	return nil
+}
+
+func (v *sliceValue[T]) Get() any { return *v.p }
+
+func (v *sliceValue[T]) String() string {
+	if v.p == nil {
+		return ""
+	}
+	toks := make([]string, len(*v.p))
+	for i, elem := range *v.p {
+		toks[i] = fmt.Sprint(elem)
+	}
+	return joinEscaped(toks, v.sep)
+}
+
+func (v *sliceValue[T]) clone() Value {
+	c := *v
+	c.p = cloned(v.p)
+	return &c
+}
+
+func (v *sliceValue[T]) typeName() string { return "list" }
+
+// splitEscaped splits s on sep, like strings.Split, except that a backslash
+// before sep makes it part of the element and two backslashes stand for one.
+// Any other backslash is kept as is.
+func splitEscaped(s, sep string) []string {
+	var elems []string
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		switch {
+		case strings.HasPrefix(s[i:], `\`+sep):
+			b.WriteString(sep)
+			i += 1 + len(sep)
+		case strings.HasPrefix(s[i:], `\\`):
+			b.WriteByte('\\')
+			i += 2
+		case strings.HasPrefix(s[i:], sep):
+			elems = append(elems, b.String())
+			b.Reset()
+			i += len(sep)
+		default:
+			b.WriteByte(s[i])
+			i++
+		}
+	}
+	return append(elems, b.String())
+}
+
+// joinEscaped joins elems with sep, escaping backslashes and separators
+// in the elements so that splitEscaped returns elems.
+func joinEscaped(elems []string, sep string) string {
+	r := strings.NewReplacer(`\`, `\\`, sep, `\`+sep)
+	escaped := make([]string, len(elems))
+	for i, elem := range elems {
+		escaped[i] = r.Replace(elem)
+	}
+	return strings.Join(escaped, sep)
+}
+
+// -- regexpMatchValue
+type regexpMatchValue struct {
+	p       *string
+	pattern *regexp.Regexp
+}
+
+func newRegexpMatchValue(val string, p *string, pattern *regexp.Regexp) (*regexpMatchValue, error) {
+	if !pattern.MatchString(val) {
+		return nil, fmt.Errorf("default %q does not match %s", val, pattern)
+	}
+	*p = val
+	return &regexpMatchValue{p: p, pattern: pattern}, nil
+}
+
+func (r *regexpMatchValue) Set(s string) error {
+	if !r.pattern.MatchString(s) {
+		return fmt.Errorf("%w: does not match %s", errParse, r.pattern)
+	}
+	*r.p = s
+	return nil
+}
+
+func (r *regexpMatchValue) Get() any { return *r.p }
+
+func (r *regexpMatchValue) String() string {
+	if r.p == nil {
+		return ""
+	}
+	return *r.p
+}
+
+func (r *regexpMatchValue) clone() Value {
+	c := *r
+	c.p = cloned(r.p)
+	return &c
+}
+
+// -- pathValue
+type pathValue struct {
+	p    *string
+	mode PathMode
+}
+
+func newPathValue(val string, p *string, mode PathMode) *pathValue {
+	*p = val
+	return &pathValue{p: p, mode: mode}
+}
+
+func (v *pathValue) Set(s string) error {
+	if v.mode != PathNone {
+		info, err := os.Stat(s)
+		switch {
+		case err != nil:
+			return err
+		case v.mode == PathMustBeDir && !info.IsDir():
+			return fmt.Errorf("%s is not a directory", s)
+		case v.mode == PathMustBeFile && !info.Mode().IsRegular():
+			return fmt.Errorf("%s is not a regular file", s)
+		}
+	}
+	*v.p = s
+	return nil
+}
+
+func (v *pathValue) Get() any { return *v.p }
+
+func (v *pathValue) String() string {
+	if v.p == nil {
+		return ""
+	}
+	return *v.p
+}
+
+func (v *pathValue) clone() Value {
+	c := *v
+	c.p = cloned(v.p)
+	return &c
+}
+
+// -- urlValue
+type urlValue struct {
+	p      *url.URL
+	redact bool // mask the password when displayed
+}
+
+func newURLValue(val *url.URL, p *url.URL, redact bool) *urlValue {
+	if val != nil {
+		*p = *val
+	}
+	return &urlValue{p: p, redact: redact}
+}
+
+func (u *urlValue) Set(s string) error {
+	v, err := url.Parse(s)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errParse, err.(*url.Error).Err)
+	}
+	*u.p = *v
+	return nil
+}
+
+func (u *urlValue) Get() any { return u.p }
+
+func (u *urlValue) String() string {
+	if u.p == nil {
+		return ""
+	}
+	return u.p.String()
+}
+
+func (u *urlValue) clone() Value {
+	c := *u
+	c.p = cloned(u.p)
+	return &c
+}
+
+// masked returns the URL s with its password masked, for display.
+func (u *urlValue) masked(s string) string {
+	if !u.redact {
+		return s
+	}
+	v, err := url.Parse(s)
+	if err != nil {
+		return s
+	}
+	return strings.Replace(v.Redacted(), ":xxxxx@", ":****@", 1)
+}
+
+// -- cronValue
+type cronValue struct {
+	p       *string
+	seconds bool // the expression has a leading seconds field
+}
+
+func newCronValue(val string, p *string, seconds bool) (*cronValue, error) {
+	c := &cronValue{p: p, seconds: seconds}
+	if err := c.check(val); err != nil {
+		return nil, fmt.Errorf("default %q: %v", val, err)
+	}
+	*p = val
+	return c, nil
+}
+
+// A cronField describes the values accepted by a field of a cron expression.
+type cronField struct {
+	name     string
+	min, max int
+	names    []string // names of the values from min, if any
+}
+
+var (
+	cronSeconds = cronField{name: "second", min: 0, max: 59}
+	cronFields  = []cronField{
+		{name: "minute", min: 0, max: 59},
+		{name: "hour", min: 0, max: 23},
+		{name: "day of month", min: 1, max: 31},
+		{name: "month", min: 1, max: 12, names: []string{"JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL", "AUG", "SEP", "OCT", "NOV", "DEC"}},
+		// both 0 and 7 are Sunday
+		{name: "day of week", min: 0, max: 7, names: []string{"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT"}},
+	}
+	cronMacros = []string{"@yearly", "@annually", "@monthly", "@weekly", "@daily", "@midnight", "@hourly"}
+)
+
+// check reports whether s is a valid cron expression.
+func (c *cronValue) check(s string) error {
+	if strings.HasPrefix(s, "@") {
+		if !slices.Contains(cronMacros, s) {
+			return fmt.Errorf("%w: unknown macro %s", errParse, s)
+		}
+		return nil
+	}
+	fields := cronFields
+	if c.seconds {
+		fields = append([]cronField{cronSeconds}, fields...)
+	}
+	parts := strings.Fields(s)
+	if len(parts) != len(fields) {
+		return fmt.Errorf("%w: expected %d fields, got %d", errParse, len(fields), len(parts))
+	}
+	for i, f := range fields {
+		if err := f.check(parts[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// check reports whether s, a list of values, ranges and steps,
+// is valid for the field.
+func (f cronField) check(s string) error {
+	for _, item := range strings.Split(s, ",") {
+		item, step, hasStep := strings.Cut(item, "/")
+		if hasStep {
+			if n, err := strconv.Atoi(step); err != nil || n <= 0 {
+				return fmt.Errorf("%w: invalid step %q in %s field", errParse, step, f.name)
+			}
+		}
+		if item == "*" {
+			continue
+		}
+		lo, hi, isRange := strings.Cut(item, "-")
+		from, err := f.value(lo)
+		if err != nil {
+			return err
+		}
+		if !isRange {
+			continue
+		}
+		to, err := f.value(hi)
+		if err != nil {
+			return err
+		}
+		if from > to {
+			return fmt.Errorf("%w: invalid range %s in %s field", errParse, item, f.name)
+		}
+	}
+	return nil
+}
+
+// value returns the value of s, a number or a name, in the field.
+func (f cronField) value(s string) (int, error) {
+	for i, name := range f.names {
+		if strings.EqualFold(name, s) {
+			return f.min + i, nil
+		}
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid value %q in %s field", errParse, s, f.name)
+	}
+	if v < f.min || v > f.max {
+		return 0, &boundsError{fmt.Sprintf("%d out of range [%d,%d] in %s field", v, f.min, f.max, f.name)}
+	}
+	return v, nil
+}
+
+func (c *cronValue) Set(s string) error {
+	if err := c.check(s); err != nil {
+		return err
+	}
+	*c.p = s
+	return nil
+}
+
+func (c *cronValue) Get() any { return *c.p }
+
+func (c *cronValue) String() string {
+	if c.p == nil {
+		return ""
+	}
+	return *c.p
+}
+
+func (c *cronValue) clone() Value {
+	d := *c
+	d.p = cloned(c.p)
+	return &d
+}
+
+// -- stringSliceValue
+type stringSliceValue struct {
+	p    *[]string
+	trim bool // trim the white space around elements
+}
+
+func newStringSliceValue(val []string, p *[]string, trim bool) *stringSliceValue {
+	*p = val
+	return &stringSliceValue{p: p, trim: trim}
+}
+
+func (v *stringSliceValue) Set(s string) error {
+	elems := []string{}
+	if s != "" {
+		elems = splitEscaped(s, ",")
+	}
+	if v.trim {
+		for i := range elems {
+			elems[i] = strings.TrimSpace(elems[i])
+		}
+	}
+	*v.p = elems
+	return nil
+}
+
+func (v *stringSliceValue) Get() any { return *v.p }
+
+func (v *stringSliceValue) String() string {
+	if v.p == nil {
+		return ""
+	}
+	return joinEscaped(*v.p, ",")
+}
+
+func (v *stringSliceValue) clone() Value {
+	c := *v
+	c.p = cloned(v.p)
+	return &c
+}
+
+// -- structSliceValue
+type structSliceValue[T any] struct {
+	p                          *[]T
+	recordSep, fieldSep, kvSep string
+	fields                     map[string]int // env tag -> field index
+}
+
+func newStructSliceValue[T any](val []T, p *[]T, recordSep, fieldSep, kvSep string) *structSliceValue[T] {
+	typ := reflect.TypeFor[T]()
+	if typ.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("element type %v is not a struct", typ))
+	}
+	fields := make(map[string]int)
+	for i := range typ.NumField() {
+		f := typ.Field(i)
+		key, ok := f.Tag.Lookup("env")
+		if !ok {
+			continue
+		}
+		if _, ok := lookupType(f.Type); !ok {
+			panic(fmt.Sprintf("field %s of %v has type %v with no registered Value", f.Name, typ, f.Type))
+		}
+		fields[key] = i
+	}
+	*p = val
+	return &structSliceValue[T]{p: p, recordSep: recordSep, fieldSep: fieldSep, kvSep: kvSep, fields: fields}
+}
+
+func (v *structSliceValue[T]) Set(s string) error {
+	records := []T{}
+	if s == "" {
+		*v.p = records
+		return nil
+	}
+	for i, record := range strings.Split(s, v.recordSep) {
+		var elem T
+		rv := reflect.ValueOf(&elem).Elem()
+		for _, field := range strings.Split(record, v.fieldSep) {
+			key, value, ok := strings.Cut(field, v.kvSep)
+			if !ok {
+				return fmt.Errorf("record %d: field %q is not a %s-separated pair", i, field, v.kvSep)
+			}
+			idx, ok := v.fields[key]
+			if !ok {
+				return fmt.Errorf("record %d: unknown field %q", i, key)
+			}
+			f := rv.Field(idx)
+			factory, _ := lookupType(f.Type())
+			if err := factory(f.Addr().Interface(), f.Interface()).Set(value); err != nil {
+				return fmt.Errorf("record %d: field %s: %w", i, key, err)
+			}
+		}
+		records = append(records, elem)
+	}
+	*v.p = records
+	return nil
+}
+
+func (v *structSliceValue[T]) Get() any { return *v.p }
+
+func (v *structSliceValue[T]) String() string {
+	if v.p == nil {
+		return ""
+	}
+	keys := slices.SortedFunc(maps.Keys(v.fields), func(a, b string) int {
+		return v.fields[a] - v.fields[b]
+	})
+	records := make([]string, len(*v.p))
+	for i, elem := range *v.p {
+		rv := reflect.ValueOf(elem)
+		fields := make([]string, len(keys))
+		for j, key := range keys {
+			f := rv.Field(v.fields[key])
+			factory, _ := lookupType(f.Type())
+			// format a copy, the factory stores the default in its variable
+			tmp := reflect.New(f.Type())
+			fields[j] = key + v.kvSep + factory(tmp.Interface(), f.Interface()).String()
+		}
+		records[i] = strings.Join(fields, v.fieldSep)
+	}
+	return strings.Join(records, v.recordSep)
+}
+
+func (v *structSliceValue[T]) clone() Value {
+	c := *v
+	c.p = cloned(v.p)
+	return &c
+}
+
+func (v *structSliceValue[T]) typeName() string { return "list" }
+
+// -- atomicStringValue
+type atomicStringValue struct{ p *atomic.Pointer[string] }
+
+func newAtomicStringValue(val string, p *atomic.Pointer[string]) *atomicStringValue {
+	p.Store(&val)
+	return &atomicStringValue{p}
+}
+
+func (s *atomicStringValue) Set(val string) error {
+	s.p.Store(&val)
+	return nil
+}
+
+func (s *atomicStringValue) Get() any { return *s.p.Load() }
+
+func (s *atomicStringValue) String() string {
+	if s.p == nil || s.p.Load() == nil {
+		return ""
+	}
+	return *s.p.Load()
+}
+
+func (s *atomicStringValue) clone() Value {
+	p := new(atomic.Pointer[string])
+	p.Store(s.p.Load())
+	return &atomicStringValue{p}
+}
+
+// -- atomicInt64Value
+type atomicInt64Value struct{ p *atomic.Int64 }
+
+func newAtomicInt64Value(val int64, p *atomic.Int64) *atomicInt64Value {
+	p.Store(val)
+	return &atomicInt64Value{p}
+}
+
+func (i *atomicInt64Value) Set(s string) error {
+	v, err := strconv.ParseInt(s, 0, 64)
+	if err != nil {
+		return numError(err)
+	}
+	i.p.Store(v)
+	return nil
+}
+
+func (i *atomicInt64Value) Get() any { return i.p.Load() }
+
+func (i *atomicInt64Value) String() string {
+	if i.p == nil {
+		return "0"
+	}
+	return strconv.FormatInt(i.p.Load(), 10)
+}
+
+func (i *atomicInt64Value) clone() Value {
+	p := new(atomic.Int64)
+	p.Store(i.p.Load())
+	return &atomicInt64Value{p}
+}
+
+// -- optionalValue
+type optionalValue[T comparable] struct {
+	p     *Optional[T]
+	value Value // stores into p.Value
+}
+
+func newOptionalValue[T comparable](val T, p *Optional[T], newValue func(T, *T) Value) *optionalValue[T] {
+	*p = Optional[T]{}
+	return &optionalValue[T]{p: p, value: newValue(val, &p.Value)}
+}
+
+func (o *optionalValue[T]) Set(s string) error {
+	if err := o.value.Set(s); err != nil {
+		return err
+	}
+	o.p.Set = true
+	return nil
+}
+
+func (o *optionalValue[T]) Get() any { return *o.p }
+
+func (o *optionalValue[T]) String() string {
+	if o.value == nil {
+		return ""
+	}
+	return o.value.String()
+}
+
+// IsZero implements ZeroValuer, as the zero optionalValue has no value to format.
+func (o *optionalValue[T]) IsZero() bool {
+	var zero T
+	return o.p.Value == zero
+}
+
+func (o *optionalValue[T]) unwrap() Value { return o.value }
+
+// -- textValue
+type textValue struct{ p encoding.TextUnmarshaler }
+
+func newTextValue(val encoding.TextUnmarshaler, p encoding.TextUnmarshaler) textValue {
+	ptrVal := reflect.ValueOf(p)
+	if ptrVal.Kind() != reflect.Ptr {
+		panic("variable value type must be a pointer")
+	}
+	defVal := reflect.ValueOf(val)
+	if defVal.Kind() != reflect.Ptr {
+		defVal = defVal.Elem()
+	}
+	if defVal.Type() != ptrVal.Type().Elem() {
+		panic(fmt.Sprintf("default type value does not match variable type: %v != %v", defVal.Type(), ptrVal.Type().Elem()))
+	}
+	ptrVal.Elem().Set(defVal)
+	return textValue{p}
+}
+
+func (v textValue) Set(s string) error {
+	return v.p.UnmarshalText([]byte(s))
+}
+
+func (v textValue) Get() any {
+	return v.p
+}
+
+func (v textValue) String() string {
+	if m, ok := v.p.(encoding.TextMarshaler); ok {
+		if b, err := m.MarshalText(); err == nil {
+			return string(b)
+		}
+	}
+	return ""
+}
+
+// -- funcValue
+type funcValue func(string) error
+
+func (f funcValue) Set(s string) error { return f(s) }
+
+func (f funcValue) String() string { return "" }
+
+func (f funcValue) Get() any {
+	return nil
+}
+
+// -- boolFuncValue
+type boolFuncValue func(string) error
+
+func (f boolFuncValue) Set(s string) error { return f(s) }
+
+func (f boolFuncValue) String() string { return "" }
+
+func (f boolFuncValue) Get() any { return nil }
+
+// -- tupleValue
+type tupleValue struct {
+	sep     string
+	parsers []func(string) error
+}
+
+func (t tupleValue) Set(s string) error {
+	fields := strings.Split(s, t.sep)
+	if len(fields) != len(t.parsers) {
+		return fmt.Errorf("%w: expected %d fields separated by %q, got %d", errParse, len(t.parsers), t.sep, len(fields))
+	}
+	for i, field := range fields {
+		if err := t.parsers[i](field); err != nil {
+			return fmt.Errorf("field %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func (t tupleValue) String() string { return "" }
+
+func (t tupleValue) Get() any { return nil }
+
+// A SignPolicy defines the sign of the durations accepted by [EnvSet.SignedDurationVar].
+type SignPolicy int
+
+// These constants define the durations accepted by [EnvSet.SignedDurationVar].
+const (
+	AllowNegative      SignPolicy = iota // any duration, such as "-5m" for five minutes before
+	RequirePositive                      // durations greater than zero
+	RequireNonNegative                   // durations greater than or equal to zero
+)
+
+// A PathMode defines the checks made on the paths accepted by [EnvSet.PathVar].
+type PathMode int
+
+// These constants define the checks made on the paths accepted by [EnvSet.PathVar].
+const (
+	PathNone       PathMode = iota // no check, any path is accepted
+	PathMustExist                  // the path exists
+	PathMustBeDir                  // the path is a directory
+	PathMustBeFile                 // the path is a regular file
+)
+
+// An Optional holds the value of a variable defined by [EnvSet.OptionalStringVar]
+// and the like, and whether the variable was set, so that a variable set to its
+// default value can be told apart from one that was not set.
+type Optional[T any] struct {
+	Value T
+	Set   bool
+}
+
+// A NameCase defines how [EnvSet.Parse] matches the names of the environment
+// with the names of the variables, see [EnvSet.SetNameCase].
+type NameCase int
+
+// These constants define how names are matched.
+const (
+	NameAsIs  NameCase = iota // names match as they are
+	NameUpper                 // names match once converted to upper case
+	NameLower                 // names match once converted to lower case
+)
+
+// convert returns name converted to the case c.
+func (c NameCase) convert(name string) string {
+	switch c {
+	case NameUpper:
+		return strings.ToUpper(name)
+	case NameLower:
+		return strings.ToLower(name)
+	}
+	return name
+}
+
+// A LogFormat is the output format of a logger, as set by [EnvSet.LogFormatVar].
+type LogFormat string
+
+// The log formats accepted by [EnvSet.LogFormatVar].
+const (
+	LogFormatJSON   LogFormat = "json"
+	LogFormatText   LogFormat = "text"
+	LogFormatLogfmt LogFormat = "logfmt"
+)
+
+// logFormats lists the names of the log formats.
+var logFormats = []string{string(LogFormatJSON), string(LogFormatText), string(LogFormatLogfmt)}
+
+// Value is the interface to the dynamic value stored in a Spec.
+// (The default value is represented as a string.)
+//
+// Set is called once, in declaration order, for each variable present.
+// The env package may call the [String] method with a zero-valued receiver,
+// such as a nil pointer.
+//
+// Get allows the contents of Value to be retrieved.
+type Value interface {
+	String() string
+	Set(string) error
+	Get() any
+}
+
+// ZeroValuer is an optional interface for a [Value] whose zero value is not
+// described by the [String] method of a zero-valued receiver.
+// IsZero reports whether the value currently held is the zero value of its type;
+// it is used by [EnvSet.PrintDefaults] to decide whether to print the default
+// value of a variable that holds it.
+type ZeroValuer interface {
+	IsZero() bool
+}
+
+// ErrorHandling defines how [EnvSet.Parse] behaves if the parse fails.
+// Only [ContinueOnError] leaves the decision to the caller, so it is the only
+// mode libraries should use on the sets they parse.
+type ErrorHandling int
+
+// These constants cause [EnvSet.Parse] to behave as described if the parse fails.
+const (
+	ContinueOnError ErrorHandling = iota // returns a descriptive error
+	ExitOnError                          // Call os.Exit(2)
+	PanicOnError                         // Call panic with a descriptive error
+)
+
+// A EnvSet represents a set of defined environment variables. The zero value of a EnvSet
+// has no name.
+//
+// [Var] name must be unique within an EnvSet. An attempt to define a variable whose
+// name is already in use will cause a panic.
+type EnvSet struct {
+	// Usage is the function called when an error occur while parsing environment variables.
+	// The field is a function (not a method) that may be changed to point to a
+	// custom error handler. What happens after Usage is called depends on the
+	// ErrorHandling setting; this defaults to ExitOnError, which exits the program
+	// after calling Usage.
+	Usage func()
+
+	name             string
+	parsed           bool
+	actual           map[string]*Spec
+	formal           map[string]*Spec
+	environment      []string
+	next             int // index in environment of the next variable to parse
+	errorHandling    ErrorHandling
+	output           io.Writer                         // nil means stderr; use Output() accessor
+	undef            map[string]string                 // variables which didn't exists at the time of set
+	fallback         map[string]string                 // fallback name -> canonical name
+	names            map[string][]string               // canonical name -> accepted names, by priority
+	pending          map[string]candidate              // best fallback candidate seen during Parse
+	accessMu         sync.Mutex                        // protects accesses
+	accesses         map[string]int                    // number of reads through Get
+	requireAll       bool                              // every variable must be set, see SetRequireAll
+	exempt           map[string]bool                   // variables exempted from requireAll
+	display          func(*Spec, string) string        // transforms values for display, see SetDisplayFunc
+	report           *Report                           // non-nil during ParseReport
+	extendedBool     bool                              // accept yes/no, on/off, enabled/disabled for bool variables
+	lazyMu           sync.Mutex                        // serializes the loading of lazy variables
+	lazy             map[string]bool                   // variables parsed on first access, skipped by Parse
+	isoDuration      bool                              // accept ISO-8601 durations for duration variables
+	conditions       map[string][]func(*EnvSet) bool   // conditions under which a variable is required
+	templateData     any                               // data for values as templates, see SetTemplateData
+	suggest          bool                              // suggest names for unknown variables
+	notifyMu         sync.Mutex                        // protects subscribers
+	subscribers      map[string][]chan<- string        // channels notified of changes by Reload
+	strictDefaults   bool                              // check that default values round-trip, see SetStrictDefaults
+	onSet            map[string][]func(any)            // callbacks run when a variable is set
+	experimental     map[string]bool                   // variables applied only when the experimental gate is on
+	gate             string                            // name of the experimental gate, see SetExperimentalGate
+	gateOn           bool                              // the experimental gate is on for the current Parse
+	fileRef          map[string]bool                   // variables accepting @file references, see AllowFileRef
+	quorums          []quorum                          // groups of variables of which some must be set, see RequireAtLeast
+	tableWidth       int                               // maximum width of descriptions in PrintTable, 0 for no limit
+	inverted         map[string]bool                   // bool variables storing the negation of their value
+	recording        *Recording                        // entries of the next Parse, see Record
+	rejectControl    bool                              // reject values with control characters, see SetRejectControlChars
+	allowTab         bool                              // tabs are not rejected as control characters
+	defaultExpansion bool                              // expand references in default values, see SetDefaultExpansion
+	maxValueLen      int                               // maximum length of a value, 0 for no limit
+	trimValues       bool                              // trim whitespace around values, see SetTrimValues
+	allowlist        map[string]bool                   // names Parse reads, if not empty, see SetAllowlist
+	helpOutput       io.Writer                         // nil means stdout; use HelpOutput() accessor
+	sealed           bool                              // the process environment is never read, see SetSealed
+	postParse        map[string]func(any) (any, error) // see SetPostParse
+	nameCase         NameCase                          // case names are converted to for matching, see SetNameCase
+	required         map[string]bool                   // variables that must be set, see Required
+	prefix           string                            // prefix of the names of the environment variables, see SetPrefix
+	applied          map[string]string                 // text last applied on top of the default of each variable, see reload
+	foundAs          map[string]string                 // name each set variable was found under, see FoundAs
+	folded           map[string]string                 // declared names by their converted name, see SetNameCase
+}
+
+// A candidate is a value for a variable with fallbacks that was seen
+// during Parse but not yet applied.
+type candidate struct {
+	rank  int    // index of name in the accepted names
+	name  string // name as it appeared in the environment
+	value string
+}
+
+// A quorum requires at least n of the named variables to be set.
+type quorum struct {
+	n     int
+	names []string
+}
+
+// A Spec represents the state of an environment variable.
+type Spec struct {
+	Name        string         // name as it appears in environment
+	Description string         // short description
+	Value       Value          // value as set
+	DefValue    string         // default value (as text); for description message
+	Metadata    map[string]any // arbitrary data attached by SetMeta
+}
+
+// sortVariables returns the variables as a slice in lexicographical sorted order.
+func sortVariables(vars map[string]*Spec) []*Spec {
+	result := make([]*Spec, len(vars))
+	i := 0
+	for _, s := range vars {
+		result[i] = s
+		i++
+	}
+	slices.SortFunc(result, func(a, b *Spec) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return result
+}
+
+// Output returns the destination for description and errro messages. [os.Stderr] is returned if
+// output was not set or was set to nil.
+func (e *EnvSet) Output() io.Writer {
+	if e.output == nil {
+		return os.Stderr
+	}
+	return e.output
+}
+
+// SetHelpOutput sets the destination for the usage message printed when help
+// is requested by setting HELP or H. If w is nil, [os.Stdout] is used.
+// The usage message printed on errors still goes to [EnvSet.Output].
+func (e *EnvSet) SetHelpOutput(w io.Writer) {
+	e.helpOutput = w
+}
+
+// HelpOutput returns the destination for the usage message printed when help
+// is requested. This is [os.Stdout] if not set or was set to nil.
+func (e *EnvSet) HelpOutput() io.Writer {
+	if e.helpOutput == nil {
+		return os.Stdout
+	}
+	return e.helpOutput
+}
+
+// Name returns the name of the environment set.
+func (e *EnvSet) Name() string {
+	return e.name
+}
+
+// Parsed reports whether e.Parse has been called.
+func (e *EnvSet) Parsed() bool {
+	return e.parsed
+}
+
+// Parsed reports whether the environment has been parsed.
+func Parsed() bool {
+	return Environment.Parsed()
+}
+
+// NVar returns the number of variables that have been set.
+func (e *EnvSet) NVar() int { return len(e.actual) }
+
+// NVar returns the number of variables of the default set that have been set.
+func NVar() int { return Environment.NVar() }
+
+// NDefined returns the number of variables that have been defined.
+func (e *EnvSet) NDefined() int { return len(e.formal) }
+
+// NDefined returns the number of variables of the default set that have been defined.
+func NDefined() int { return Environment.NDefined() }
+
+// ErrorHandling returns the error handling behavior of the variable set.
+func (e *EnvSet) ErrorHandling() ErrorHandling {
+	return e.errorHandling
+}
+
+// WillExit reports whether [EnvSet.Parse] exits the program if the parse fails,
+// that is whether the set uses [ExitOnError].
+func (e *EnvSet) WillExit() bool {
+	return e.errorHandling == ExitOnError
+}
+
+// WillPanic reports whether [EnvSet.Parse] panics if the parse fails,
+// that is whether the set uses [PanicOnError].
+func (e *EnvSet) WillPanic() bool {
+	return e.errorHandling == PanicOnError
+}
+
+// Environment returns the environment list given to the last call to
+// [EnvSet.Parse], or nil if the set was not parsed yet.
+// The list is not modified by Parse.
+func (e *EnvSet) Environment() []string {
+	return e.environment
+}
+
+// SetOutput sets the destination for usage and error messages.
+// If output is nil, [os.Stderr] is used.
+func (e *EnvSet) SetOutput(output io.Writer) {
+	e.output = output
+}
+
+// SetDisplayFunc sets the function used to transform the value of a variable
+// whenever it is displayed, such as in the output of [EnvSet.PrintDefaults]
+// or in the [ParseError] reporting a value that could not be set.
+// The function receives the variable and its value as text and returns the
+// text to display, for example a redacted or truncated version of it:
+//
+//	e.SetDisplayFunc(func(spec *env.Spec, value string) string {
+//		if spec.Name == "API_TOKEN" {
+//			return "****"
+//		}
+//		return value
+//	})
+//
+// The value of the variable is not affected. If fn is nil, values are
+// displayed as they are.
+func (e *EnvSet) SetDisplayFunc(fn func(spec *Spec, value string) string) {
+	e.display = fn
+}
+
+// displayValue returns value as it should be displayed for the variable.
+func (e *EnvSet) displayValue(spec *Spec, value string) string {
+	if m, ok := spec.Value.(interface{ masked(string) string }); ok {
+		value = m.masked(value)
+	}
+	if e.display == nil {
+		return value
+	}
+	return e.display(spec, value)
+}
+
+// SetDefaultExpansion sets whether [EnvSet.Parse] expands the references to
+// other variables, written $NAME or ${NAME}, in the default value of the
+// variables that are not set. A reference is replaced by the value of the
+// variable, itself expanded first if needed, so that
+//
+//	e.String("DATA_DIR", "${HOME}/myapp", "data directory")
+//
+// defaults to the data directory below the value of the HOME variable of the
+// set. Referencing a variable that is not defined, or a cycle of references,
+// makes Parse fail.
+func (e *EnvSet) SetDefaultExpansion(expand bool) {
+	e.defaultExpansion = expand
+}
+
+// SetNameCase sets how [EnvSet.Parse] matches the names of the environment
+// with the names of the variables: with [NameUpper] or [NameLower] both are
+// converted to that case first, so that the variable HttpPort is set by
+// HTTPPORT with NameUpper. The variables keep the name they were defined
+// with, which [EnvSet.PrintDefaults] shows. The default is [NameAsIs].
+// Defining two variables whose names convert to the same name panics, as
+// does calling SetNameCase when such variables are defined already.
+func (e *EnvSet) SetNameCase(c NameCase) {
+	folded := make(map[string]string)
+	names := slices.Concat(slices.Collect(maps.Keys(e.formal)), slices.Collect(maps.Keys(e.fallback)))
+	slices.Sort(names)
+	for _, name := range names {
+		key := c.convert(name)
+		if prev, ok := folded[key]; ok {
+			panic(e.sprintf("variables %s and %s both match %s", prev, name, key))
+		}
+		folded[key] = name
+	}
+	e.nameCase = c
+	e.folded = nil
+	if c != NameAsIs {
+		e.folded = folded
+	}
+}
+
+// SetCaseInsensitive sets whether [EnvSet.Parse] matches the names of the
+// environment with the names of the variables regardless of case, so that the
+// variable Port is set by PORT or port. It is a shorthand for [EnvSet.SetNameCase]
+// with [NameLower] or [NameAsIs] and panics in the same cases; the default
+// is to match names exactly.
+func (e *EnvSet) SetCaseInsensitive(b bool) {
+	if b {
+		e.SetNameCase(NameLower)
+	} else {
+		e.SetNameCase(NameAsIs)
+	}
+}
+
+// SetSealed sets whether the set is sealed: a sealed set never reads the
+// process environment, so that its variables only come from the input given
+// explicitly to [EnvSet.Parse], [EnvSet.ParseMap], [EnvSet.ParseFile] and the
+// like. [EnvSet.ParseEnviron] fails with [ErrSealed] and lazy variables, such
+// as those defined by [EnvSet.LazyInt], keep their default value.
+func (e *EnvSet) SetSealed(sealed bool) {
+	e.sealed = sealed
+}
+
+// lookupEnv is like os.LookupEnv for the variable name of the set: it adds the
+// prefix of the set and finds nothing if the set is sealed or if the name is
+// not in the allowlist.
+func (e *EnvSet) lookupEnv(name string) (string, bool) {
+	if e.sealed {
+		return "", false
+	}
+	if e.nameCase != NameAsIs {
+		// the name can appear in any case, match it as Parse does
+		for _, s := range os.Environ() {
+			n, value, _ := strings.Cut(s, "=")
+			if n, ok := e.match(n); ok && n == name {
+				return value, true
+			}
+		}
+		return "", false
+	}
+	name = e.prefix + name
+	if len(e.allowlist) > 0 && !e.allowlist[name] {
+		return "", false
+	}
+	return os.LookupEnv(name)
+}
+
+// SetPrefix sets the prefix shared by the names of the environment variables
+// of the set. [EnvSet.Parse] ignores the entries whose name does not start with
+// prefix and strips it from the others before matching them, so that with the
+// prefix "MYAPP_" the variable PORT is set by MYAPP_PORT and not by PORT.
+// The usage message and [EnvSet.Environ] show the prefixed names, while the
+// methods taking the name of a variable, such as [EnvSet.Lookup] and
+// [EnvSet.Set], take the bare one. HELP and H request help both with and
+// without the prefix. The names given to [EnvSet.SetAllowlist] are matched
+// before the prefix is removed, so they include it. An empty prefix, the
+// default, matches every name.
+func (e *EnvSet) SetPrefix(prefix string) {
+	e.prefix = prefix
+}
+
+// SetAllowlist restricts [EnvSet.Parse] to the environment entries with one of
+// the given names, ignoring the others even if they name a variable of the set.
+// The names are those of the environment, with the prefix set by [EnvSet.SetPrefix].
+// A variable whose names are all left out keeps its default value and, if it
+// is required, is reported as missing. The restriction applies to the
+// variables read from the process environment when first used too, such as
+// those defined by [EnvSet.LazyInt]. Calling SetAllowlist with no names
+// removes the restriction.
+func (e *EnvSet) SetAllowlist(names ...string) {
+	e.allowlist = nil
+	for _, name := range names {
+		if e.allowlist == nil {
+			e.allowlist = make(map[string]bool)
+		}
+		e.allowlist[name] = true
+	}
+}
+
+// SetTrimValues sets whether [EnvSet.Parse] removes the leading and trailing
+// white space of every value before setting it, so that "true " is accepted by
+// a bool variable. It applies to the variables of every type, strings included,
+// and is off by default as white space can be significant.
+func (e *EnvSet) SetTrimValues(trim bool) {
+	e.trimValues = trim
+}
+
+// SetMaxValueLen sets the maximum length, in bytes, of the values accepted by
+// [EnvSet.Parse]; longer values are rejected before being set. A limit of 0,
+// the default, accepts values of any length. The limit applies to the values
+// loaded by [EnvSet.ParseFile] too, which may legitimately be long when they
+// span multiple lines, such as certificates.
+func (e *EnvSet) SetMaxValueLen(n int) {
+	e.maxValueLen = n
+}
+
+// SetRejectControlChars sets whether [EnvSet.Parse] rejects the values that
+// contain control characters, such as newlines or NUL, which may be the sign of
+// malformed or injected input. Control characters are those reported by
+// [unicode.IsControl]: U+0000 to U+001F, U+007F and U+0080 to U+009F.
+// Tabs can be allowed with [EnvSet.SetAllowTab]. Values that legitimately span
+// multiple lines, such as PEM blocks, are rejected too, so the check is off
+// by default.
+func (e *EnvSet) SetRejectControlChars(reject bool) {
+	e.rejectControl = reject
+}
+
+// SetAllowTab sets whether tabs are allowed in values when control
+// characters are rejected, see [EnvSet.SetRejectControlChars].
+func (e *EnvSet) SetAllowTab(allow bool) {
+	e.allowTab = allow
+}
+
+// SetExtendedBool sets whether the bool variables of the set also accept the
+// words "yes", "on", "enabled" for true and "no", "off", "disabled" for false,
+// in any case, in addition to the values accepted by [strconv.ParseBool].
+func (e *EnvSet) SetExtendedBool(extended bool) {
+	e.extendedBool = extended
+}
+
+// boolWord translates the extended boolean words to a value accepted
+// by strconv.ParseBool. Any other value is returned unchanged.
+func boolWord(s string) string {
+	switch strings.ToLower(s) {
+	case "yes", "on", "enabled":
+		return "true"
+	case "no", "off", "disabled":
+		return "false"
+	}
+	return s
+}
+
+// SetISODuration sets whether the duration variables of the set also accept
+// ISO-8601 durations, such as "PT15M" or "P1DT12H", in addition to the values
+// accepted by [time.ParseDuration]. Years and months are not accepted as their
+// length is not fixed; a day is 24 hours and a week 7 days.
+func (e *EnvSet) SetISODuration(iso bool) {
+	e.isoDuration = iso
+}
+
+// parseISODuration parses an ISO-8601 duration of the form PnWnDTnHnMnS,
+// where the seconds can have a fractional part and a leading sign is allowed.
+func parseISODuration(s string) (time.Duration, error) {
+	orig := s
+	neg := false
+	if s != "" && (s[0] == '-' || s[0] == '+') {
+		neg = s[0] == '-'
+		s = s[1:]
+	}
+	s, ok := strings.CutPrefix(s, "P")
+	if !ok || s == "" || s == "T" {
+		return 0, fmt.Errorf("invalid ISO-8601 duration %q", orig)
+	}
+	units := map[byte]time.Duration{'W': 7 * 24 * time.Hour, 'D': 24 * time.Hour}
+	var d time.Duration
+	inTime := false
+	for s != "" {
+		if s[0] == 'T' {
+			if inTime {
+				return 0, fmt.Errorf("invalid ISO-8601 duration %q", orig)
+			}
+			inTime = true
+			units = map[byte]time.Duration{'H': time.Hour, 'M': time.Minute, 'S': time.Second}
+			s = s[1:]
+			if s == "" {
+				return 0, fmt.Errorf("invalid ISO-8601 duration %q", orig)
+			}
+			continue
+		}
+		i := strings.IndexFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+		if i <= 0 {
+			return 0, fmt.Errorf("invalid ISO-8601 duration %q", orig)
+		}
+		unit, ok := units[s[i]]
+		if !ok || (s[i] != 'S' && strings.Contains(s[:i], ".")) {
+			return 0, fmt.Errorf("invalid ISO-8601 duration %q", orig)
+		}
+		n, err := strconv.ParseFloat(s[:i], 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid ISO-8601 duration %q", orig)
+		}
+		d += time.Duration(n * float64(unit))
+		// each unit appears at most once and in order
+		for u := range units {
+			if units[u] >= unit {
+				delete(units, u)
+			}
+		}
+		s = s[i+1:]
+	}
+	if neg {
+		d = -d
+	}
+	return d, nil
+}
+
+// SetTemplateData sets the data used to expand the values of the variables
+// as [text/template] templates before they are set, for example
+//
+//	WORKER_NAME={{.Hostname}}-worker
+//
+// A template that fails to execute, including one that refers to a missing
+// key of a map, is a parse error for the variable. If data is nil, which is
+// the default, values are used as they are.
+func (e *EnvSet) SetTemplateData(data any) {
+	e.templateData = data
+}
+
+// SetSuggestions sets whether [EnvSet.Parse] prints a suggestion to the output
+// for each environment entry that matches no variable but is close to the
+// name of one, such as
+//
+//	env: unknown variable DTABASE_URL; did you mean DATABASE_URL?
+//
+// Names are close if their edit distance is at most 2.
+func (e *EnvSet) SetSuggestions(suggest bool) {
+	e.suggest = suggest
+}
+
+// maxSuggestDistance is the largest edit distance for which a name is suggested.
+const maxSuggestDistance = 2
+
+// suggestName prints the accepted name closest to the unknown name, if any.
+func (e *EnvSet) suggestName(name string) {
+	best, bestDist := "", maxSuggestDistance+1
+	for _, accepted := range e.AcceptedNames() {
+		if d := distance(name, accepted, maxSuggestDistance); d < bestDist {
+			best, bestDist = accepted, d
+		}
+	}
+	if best != "" {
+		fmt.Fprintf(e.Output(), "env: unknown variable %s; did you mean %s?\n", name, best)
+	}
+}
+
+// distance returns the Levenshtein distance between a and b, or limit+1
+// if it is larger than limit.
+func distance(a, b string, limit int) int {
+	if len(a)-len(b) > limit || len(b)-len(a) > limit {
+		return limit + 1
+	}
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		rowMin := cur[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			rowMin = min(rowMin, cur[j])
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		prev, cur = cur, prev
+	}
+	return min(prev[len(b)], limit+1)
+}
+
+// SetStrictDefaults sets whether [EnvSet.Var] checks that the default value of
+// a variable, as returned by its String method, is accepted by its Set method
+// and formats back to the same text. Definitions failing the check panic,
+// which catches defaults that the variable would not accept from the environment.
+// The check is done on a copy of the value, so the variable is left unchanged.
+// Only the values of this package can be copied: variables defined with
+// [EnvSet.Func], [EnvSet.BoolFunc], [EnvSet.TupleVar] and [EnvSet.TextVar],
+// and those defined with a [Value] implemented elsewhere, are not checked.
+func (e *EnvSet) SetStrictDefaults(strict bool) {
+	e.strictDefaults = strict
+}
+
+// VisitAll visits the variables in lexicographical order, calling fn for each.
+// It visits all, even those not set.
+func (e *EnvSet) VisitAll(fn func(*Spec)) {
+	for _, spec := range sortVariables(e.formal) {
+		fn(spec)
+	}
+}
+
+// VisitAll visits the variables in lexicographical order, calling
+// fn for each. It visits all, even those not set.
+func VisitAll(fn func(*Spec)) {
+	Environment.VisitAll(fn)
+}
+
+// VisitAllCopy is like [EnvSet.VisitAll] but calls fn with a copy of each
+// variable, so that fn cannot change the variables of the set. It is meant
+// for read-only tooling such as documentation generators.
+func (e *EnvSet) VisitAllCopy(fn func(Spec)) {
+	for _, spec := range sortVariables(e.formal) {
+		c := *spec
+		c.Metadata = maps.Clone(spec.Metadata)
+		fn(c)
+	}
+}
+
+// VisitAllCopy is like [VisitAll] but calls fn with a copy of each variable.
+func VisitAllCopy(fn func(Spec)) {
+	Environment.VisitAllCopy(fn)
+}
+
+// VisitFunc visits the variables in lexicographical order, calling fn
+// for each variable for which pred returns true, even those not set.
+func (e *EnvSet) VisitFunc(pred func(*Spec) bool, fn func(*Spec)) {
+	for _, spec := range sortVariables(e.formal) {
+		if pred(spec) {
+			fn(spec)
+		}
+	}
+}
+
+// VisitFunc visits the variables in lexicographical order, calling fn
+// for each variable for which pred returns true.
+func VisitFunc(pred func(*Spec) bool, fn func(*Spec)) {
+	Environment.VisitFunc(pred, fn)
+}
+
+// Tree returns the values of the variables whose name starts with prefix as
+// a tree of maps: the rest of each name is split on sep and, in lower case,
+// gives the path to the value, as returned by [Value.Get]. For instance, with
+// prefix "APP_" and sep "_" the variables APP_DB_HOST and APP_DB_PORT give
+//
+//	map[string]any{"db": map[string]any{"host": "localhost", "port": 5432}}
+//
+// It returns an error if a name is the path to both a value and a map.
+func (e *EnvSet) Tree(prefix, sep string) (map[string]any, error) {
+	tree := make(map[string]any)
+	for _, spec := range sortVariables(e.formal) {
+		rest, ok := strings.CutPrefix(spec.Name, prefix)
+		if !ok {
+			continue
+		}
+		path := strings.Split(strings.ToLower(rest), sep)
+		node := tree
+		for i, key := range path[:len(path)-1] {
+			switch child := node[key].(type) {
+			case nil:
+				m := make(map[string]any)
+				node[key] = m
+				node = m
+			case map[string]any:
+				node = child
+			default:
+				return nil, fmt.Errorf("variable %s conflicts with the value at %s", spec.Name, strings.Join(path[:i+1], sep))
+			}
+		}
+		leaf := path[len(path)-1]
+		if _, ok := node[leaf]; ok {
+			return nil, fmt.Errorf("variable %s conflicts with the values below %s", spec.Name, strings.Join(path, sep))
+		}
+		node[leaf] = spec.Value.Get()
+	}
+	return tree, nil
+}
+
+// Tree returns the values of the variables whose name starts with prefix as
+// a tree of maps. See [EnvSet.Tree].
+func Tree(prefix, sep string) (map[string]any, error) {
+	return Environment.Tree(prefix, sep)
+}
+
+// Visit visits the variables in lexicographical order, calling fn for each.
+// It visits only those that have been set.
+func (e *EnvSet) Visit(fn func(*Spec)) {
+	for _, spec := range sortVariables(e.actual) {
+		fn(spec)
+	}
+}
+
+// Visit visits the variables in lexicographical order, calling fn
+// for each. It visits only those that have been set.
+func Visit(fn func(*Spec)) {
+	Environment.Visit(fn)
+}
+
+// Lookup returns the [Spec] of the named variable, returning nil if none exists.
+func (e *EnvSet) Lookup(name string) *Spec {
+	return e.formal[name]
+}
+
+// Lookup returns the [Spec] of the named variable, returning nil if none exists.
+func Lookup(name string) *Spec {
+	return Environment.formal[name]
+}
+
+// Get returns the value of the variable name, as returned by its [Value.Get]
+// method, or nil if no such variable is defined.
+// Reads through Get are counted and reported by [EnvSet.AccessCounts]; reads
+// through the pointers returned by [EnvSet.Bool], [EnvSet.Int] and the like
+// cannot be observed by the set and are not counted.
+func (e *EnvSet) Get(name string) any {
+	spec, ok := e.formal[name]
+	if !ok {
+		return nil
+	}
+	e.accessMu.Lock()
+	if e.accesses == nil {
+		e.accesses = make(map[string]int)
+	}
+	e.accesses[name]++
+	e.accessMu.Unlock()
+	return spec.Value.Get()
+}
+
+// Get returns the value of the variable name, as returned by its [Value.Get]
+// method, or nil if no such variable is defined.
+// Reads through Get are counted and reported by [AccessCounts].
+func Get(name string) any {
+	return Environment.Get(name)
+}
+
+// AccessCounts returns, for every defined variable, the number of times it
+// was read through [EnvSet.Get]. Variables that were never read have a count
+// of zero, which helps spotting configuration that is not used.
+func (e *EnvSet) AccessCounts() map[string]int {
+	e.accessMu.Lock()
+	defer e.accessMu.Unlock()
+	counts := make(map[string]int, len(e.formal))
+	for name := range e.formal {
+		counts[name] = e.accesses[name]
+	}
+	return counts
+}
+
+// AccessCounts returns, for every defined variable, the number of times it
+// was read through [Get].
+func AccessCounts() map[string]int {
+	return Environment.AccessCounts()
+}
+
+// isZeroValue determines whether the string represents the zero
+// value for a variable.
+func isZeroValue(spec *Spec, value string) (ok bool, err error) {
+	// A ZeroValuer knows whether it holds the zero value.
+	if z, ok := spec.Value.(ZeroValuer); ok && spec.Value.String() == value {
+		return z.IsZero(), nil
+	}
+	// Build a zero value of the variable's Value type, and see if the
+	// result of calling its String method equals the value passed in.
+	// This works unless the Value type is itself an interface type.
+	typ := reflect.TypeOf(spec.Value)
+	var z reflect.Value
+	if typ.Kind() == reflect.Pointer {
+		z = reflect.New(typ.Elem())
+	} else {
+		z = reflect.Zero(typ)
+	}
+	// Catch panics calling the String method, which shouldn't prevent the
+	// usage message from being printed, but that we should report to the
+	// user so that they know to fix their code.
+	defer func() {
+		if e := recover(); e != nil {
+			if typ.Kind() == reflect.Pointer {
+				typ = typ.Elem()
+			}
+			err = fmt.Errorf("panic calling String method on zero %v for variable %s: %v", typ, spec.Name, e)
+		}
+	}()
+	return value == z.Interface().(Value).String(), nil
+}
+
+// UnquoteUsage extracts a back-quoted name from the usage
+// string for an environment variable and returns it and the un-quoted usage.
+// Given "a `name` to show" it returns ("name", "a name to show").
+// If there are no back quotes, the name is an educated guess of the
+// type of the variables's value.
+func UnquoteUsage(spec *Spec) (name string, description string) {
+	// Look for a back-quoted name, but avoid the strings package.
+	description = spec.Description
+	for i := 0; i < len(description); i++ {
+		if description[i] == '`' {
+			for j := i + 1; j < len(description); j++ {
+				if description[j] == '`' {
+					name = description[i+1 : j]
+					description = description[:i] + name + description[j+1:]
+					return name, description
+				}
+			}
+			break // Only one back quote; use type name.
+		}
+	}
+	// No explicit name, so list the choices or use type if we can find one.
+	switch v := spec.Value.(type) {
+	case *logFormatValue:
+		return strings.Join(logFormats, "|"), description
+	case *regexpMatchValue:
+		return "/" + v.pattern.String() + "/", description
+	case *countValue:
+		return "count", description
+	}
+	return typeName(spec.Value), description
+}
+
+// typeName returns the name of the type of the variable's value.
+// It is "value" for types not provided by this package.
+func typeName(value Value) (name string) {
+	if w, ok := value.(interface{ unwrap() Value }); ok {
+		return typeName(w.unwrap())
+	}
+	// generic values name their type for any type argument
+	if v, ok := value.(interface{ typeName() string }); ok {
+		return v.typeName()
+	}
+	name = "value"
+	switch value.(type) {
+	case *boolValue:
+		name = "boolean"
+	case *durationValue, *namedDurationValue, *durationRangeValue, *signedDurationValue:
+		name = "duration"
+	case *float64Value, *unitValue:
+		name = "float"
+	case *percentValue, *basisPointsValue, *rolloutValue:
+		name = "percent"
+	case *scheduleValue:
+		name = "schedule"
+	case *unixTimeValue:
+		name = "epoch"
+	case *timeValue:
+		name = "time"
+	case *intValue, *int64Value, *atomicInt64Value, *countValue:
+		name = "int"
+	case *stringValue, *atomicStringValue, *logFormatValue, *regexpMatchValue:
+		name = "string"
+	case *cronValue:
+		name = "cron"
+	case *urlValue:
+		name = "url"
+	case *stringSliceValue:
+		name = "list"
+	case *pathValue:
+		name = "path"
+	case *uintValue, *uint64Value, *bitmaskValue:
+		name = "uint"
+	}
+	return
+}
+
+// PrintDefaults print, to standard error unless configured otherwise, the
+// default values of all defined environment variables in the set. See the
+// documentation for the global function PrintDefaults for more information.
+func (e *EnvSet) PrintDefaults() {
+	e.printZeroValueErrs(e.printDefaults(sortVariables(e.formal), false))
+}
+
+// PrintDefaultsMatching is like [EnvSet.PrintDefaults] but prints only the
+// variables whose name matches pattern, with the syntax of [path.Match], such
+// as "DB_*". It returns [path.ErrBadPattern] if the pattern is malformed.
+func (e *EnvSet) PrintDefaultsMatching(pattern string) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return err
+	}
+	var specs []*Spec
+	for _, spec := range sortVariables(e.formal) {
+		if ok, _ := path.Match(pattern, spec.Name); ok {
+			specs = append(specs, spec)
+		}
+	}
+	e.printZeroValueErrs(e.printDefaults(specs, false))
+	return nil
+}
+
+// PrintDefaultsMatching is like [PrintDefaults] but prints only the variables
+// whose name matches pattern.
+func PrintDefaultsMatching(pattern string) error {
+	return Environment.PrintDefaultsMatching(pattern)
+}
+
+// PrintStatus is like [EnvSet.PrintDefaults] but, for the variables that were
+// set by [EnvSet.Parse], it prints their current value followed by "(set)"
+// instead of their default value. Before Parse its output is the same as
+// the output of PrintDefaults.
+func (e *EnvSet) PrintStatus() {
+	e.printZeroValueErrs(e.printDefaults(sortVariables(e.formal), true))
+}
+
+// PrintStatus is like [PrintDefaults] but, for the variables that were set,
+// it prints their current value followed by "(set)".
+func PrintStatus() {
+	Environment.PrintStatus()
+}
+
+// PrintTable prints to w a table of all defined environment variables in the
+// set, sorted by name, with columns for the name, the type, the default value
+// and the description of each variable. Descriptions are printed on one line
+// and cut to the width set with [EnvSet.SetTableWidth].
+func (e *EnvSet) PrintTable(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tTYPE\tDEFAULT\tDESCRIPTION")
+	for _, spec := range sortVariables(e.formal) {
+		name, usage := UnquoteUsage(spec)
+		usage, _ = e.inlineDefault(spec, usage)
+		usage = strings.Join(strings.Fields(usage), " ")
+		if r := []rune(usage); e.tableWidth > 0 && len(r) > e.tableWidth {
+			usage = string(r[:max(e.tableWidth-3, 0)]) + "..."
+		}
+		def := ""
+		if isZero, err := isZeroValue(spec, spec.DefValue); err == nil && !isZero {
+			def = e.displayValue(spec, spec.DefValue)
+			if _, ok := spec.Value.(*stringValue); ok {
+				def = strconv.Quote(def)
+			}
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", strings.Join(e.envNames(spec.Name), ", "), name, def, usage)
+	}
+	tw.Flush()
+}
+
+// PrintTable prints to w a table of all defined environment variables.
+// See [EnvSet.PrintTable] for the format.
+func PrintTable(w io.Writer) {
+	Environment.PrintTable(w)
+}
+
+// SetTableWidth sets the maximum width, in characters, of the descriptions
+// printed by [EnvSet.PrintTable]. Longer descriptions are cut and end in "...".
+// A width of 0, the default, leaves descriptions untouched.
+func (e *EnvSet) SetTableWidth(width int) {
+	e.tableWidth = width
+}
+
+// printDefaults prints the description of the specs, in order, and returns
+// the errors encountered while checking for zero values.
+// If status is set, variables that were set show their current value instead.
+func (e *EnvSet) printDefaults(specs []*Spec, status bool) []error {
+	var isZeroValueErrs []error
+	for _, spec := range specs {
+		var b strings.Builder
+		fmt.Fprintf(&b, "  %s", strings.Join(e.envNames(spec.Name), ", "))
+		name, usage := UnquoteUsage(spec)
+		if len(name) > 0 {
+			b.WriteString("  ")
+			b.WriteString(name)
+		}
+		b.WriteString("\n    \t")
+		usage, inlined := e.inlineDefault(spec, usage)
+		b.WriteString(strings.ReplaceAll(usage, "\n", "\n    \t"))
+		// Print the default value only if it differs to the zero value
+		// for this variable type.
+		if status && e.actual[spec.Name] != nil {
+			if _, ok := spec.Value.(*stringValue); ok {
+				fmt.Fprintf(&b, " (value %q) (set)", e.displayValue(spec, spec.Value.String()))
+			} else {
+				fmt.Fprintf(&b, " (value %v) (set)", e.displayValue(spec, spec.Value.String()))
+			}
+		} else if inlined {
+			// the default value is in the description already
+		} else if isZero, err := isZeroValue(spec, spec.DefValue); err != nil {
+			isZeroValueErrs = append(isZeroValueErrs, err)
+		} else if !isZero {
+			if _, ok := spec.Value.(*stringValue); ok {
+				// put quotes on the value
+				fmt.Fprintf(&b, " (default %q)", e.displayValue(spec, spec.DefValue))
+			} else {
+				fmt.Fprintf(&b, " (default %v)", e.displayValue(spec, spec.DefValue))
+			}
+		}
+		if e.inverted[spec.Name] {
+			b.WriteString(" (inverted: true disables)")
+		}
+		if e.experimental[spec.Name] {
+			b.WriteString(" (experimental)")
+		}
+		fmt.Fprint(e.Output(), b.String(), "\n")
+	}
+	return isZeroValueErrs
+}
+
+// inlineDefault replaces the {{default}} token in the description usage with
+// the default value of the variable, and reports whether it did.
+func (e *EnvSet) inlineDefault(spec *Spec, usage string) (string, bool) {
+	if !strings.Contains(usage, "{{default}}") {
+		return usage, false
+	}
+	return strings.ReplaceAll(usage, "{{default}}", e.displayValue(spec, spec.DefValue)), true
+}
+
+// printZeroValueErrs prints the errors returned by printDefaults.
+func (e *EnvSet) printZeroValueErrs(errs []error) {
+	// if calling string on any zero env.values triggered a panic, print
+	// the messages after the full set of defaults so that the programmer
+	// knows to fix the panic.
+	if len(errs) > 0 {
+		fmt.Fprintln(e.Output())
+		for _, err := range errs {
+			fmt.Fprintln(e.Output(), err)
+		}
+	}
+}
+
+// typeGroups lists the headings used by PrintDefaultsByType, in order,
+// for each type name returned by typeName.
+var typeGroups = []struct {
+	name    string
+	heading string
+}{
+	{"boolean", "Booleans:"},
+	{"int", "Integers:"},
+	{"uint", "Unsigned integers:"},
+	{"float", "Floats:"},
+	{"percent", "Percentages:"},
+	{"string", "Strings:"},
+	{"duration", "Durations:"},
+	{"schedule", "Schedules:"},
+	{"epoch", "Timestamps:"},
+	{"time", "Times:"},
+	{"cron", "Cron expressions:"},
+	{"list", "Lists:"},
+	{"json", "JSON lists:"},
+	{"url", "URLs:"},
+	{"path", "Paths:"},
+	{"value", "Other:"},
+}
+
+// PrintDefaultsByType is like [EnvSet.PrintDefaults] but groups the variables
+// by the type of their value, under a heading for each type such as "Booleans:"
+// or "Durations:". Variables of a type not provided by this package are listed
+// under "Other:". Variables are sorted within each group.
+func (e *EnvSet) PrintDefaultsByType() {
+	groups := make(map[string][]*Spec)
+	for _, spec := range sortVariables(e.formal) {
+		name := typeName(spec.Value)
+		groups[name] = append(groups[name], spec)
+	}
+	var isZeroValueErrs []error
+	for _, g := range typeGroups {
+		if len(groups[g.name]) == 0 {
+			continue
+		}
+		fmt.Fprintln(e.Output(), g.heading)
+		isZeroValueErrs = append(isZeroValueErrs, e.printDefaults(groups[g.name], false)...)
+	}
+	e.printZeroValueErrs(isZeroValueErrs)
+}
+
+// PrintDefaultsByType is like [PrintDefaults] but groups the variables
+// by the type of their value.
+func PrintDefaultsByType() {
+	Environment.PrintDefaultsByType()
+}
+
+// PrintDefaults print, to standard error, unless configured otherwise, the
+// default values of all defined environment variables.
+// For an integer valued variable x, the default output has the form
+//
+// x  int
+//
+//	description-message-for-x (default: 7)
+//
+// The description message will appear on a separate line.
+// The parenthetical default is omitted if the default is the zero value for
+// the type. The listed type, here int, can be changed by placing a back-quoted
+// name in the variable's description string; the first such item in the message is taken
+// to be a parameter name to show in the message and the back quotes are
+// stripped from the message when displayed. For instance, given
+//
+//	env.String("I", "", "search `directory` for include files")
+//
+// the output will be
+//
+// I  directory
+//
+//	search directory for include files.
+//
+// The default value can be placed anywhere in the description with the
+// {{default}} token, which also omits the parenthetical default:
+//
+//	env.Duration("TIMEOUT", 5*time.Second, "requests time out after {{default}}")
+//
+// To change the destination for variable messages, call [Environment].SetOutput.
+func PrintDefaults() {
+	Environment.PrintDefaults()
+}
+
+// schemaVariable is the description of a variable written by Schema.
+type schemaVariable struct {
+	Name        string   `json:"name"`
+	Fallbacks   []string `json:"fallbacks,omitempty"`
+	Type        string   `json:"type"`
+	Description string   `json:"description"`
+	Default     string   `json:"default"`
+	Required    bool     `json:"required"`
+	Allowed     []string `json:"allowed,omitempty"`
+}
+
+// Schema writes to w a JSON description of the variables defined in the set,
+// in lexicographical order. Each variable is described by its name, the
+// type of its value, its description, its default value, whether it is
+// required and, for the variables accepting a fixed set of values such as
+// those defined by [EnvSet.LogFormatVar] and [EnvSet.EnumsVar], the values
+// allowed. The output is meant for validating deployment configurations
+// against the variables a program accepts.
+func (e *EnvSet) Schema(w io.Writer) error {
+	vars := []schemaVariable{}
+	e.VisitAll(func(spec *Spec) {
+		_, description := UnquoteUsage(spec)
+		var allowed []string
+		if c, ok := spec.Value.(interface{ choices() []string }); ok {
+			allowed = c.choices()
+		}
+		vars = append(vars, schemaVariable{
+			Name:        e.prefix + spec.Name,
+			Fallbacks:   e.envNames(spec.Name)[1:],
+			Type:        typeName(spec.Value),
+			Description: description,
+			Default:     e.displayValue(spec, spec.DefValue),
+			Required:    e.isRequired(spec.Name),
+			Allowed:     allowed,
+		})
+	})
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(vars)
+}
+
+// Schema writes to w a JSON description of the variables defined in the
+// default set, in lexicographical order.
+func Schema(w io.Writer) error {
+	return Environment.Schema(w)
+}
+
+// AcceptedNames returns, in lexicographical order, every name that is matched
+// by [EnvSet.Parse]: the names of the variables and their fallbacks.
+func (e *EnvSet) AcceptedNames() []string {
+	var names []string
+	for name := range e.formal {
+		names = append(names, e.envNames(name)...)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// AcceptedNames returns, in lexicographical order, every name that is matched
+// by [Parse].
+func AcceptedNames() []string {
+	return Environment.AcceptedNames()
+}
+
+// CheckCollisions returns, in lexicographical order, the names matched in the
+// environment, prefix included, by more than one variable of e and of the
+// other sets, such as DB_HOST in a set with prefix APP_ and HOST in a set with
+// prefix APP_DB_, which would both read APP_DB_HOST. Names that differ only
+// in case collide too on Windows, where the environment ignores case, or if a
+// set matches names regardless of case, see [EnvSet.SetNameCase].
+// CheckCollisions does not panic and can be called before Parse to report
+// the collisions of sets assembled from several packages. It returns an empty
+// slice if no names collide.
+func (e *EnvSet) CheckCollisions(others ...*EnvSet) []string {
+	sets := []*EnvSet{e}
+	for _, o := range others {
+		if !slices.Contains(sets, o) {
+			sets = append(sets, o)
+		}
+	}
+	fold := runtime.GOOS == "windows"
+	for _, s := range sets {
+		fold = fold || s.nameCase != NameAsIs
+	}
+	seen := make(map[string]int)
+	for _, s := range sets {
+		for v := range s.formal {
+			for _, name := range s.envNames(v) {
+				if fold {
+					name = strings.ToUpper(name)
+				}
+				seen[name]++
+			}
+		}
+	}
+	collisions := []string{}
+	for name, n := range seen {
+		if n > 1 {
+			collisions = append(collisions, name)
+		}
+	}
+	slices.Sort(collisions)
+	return collisions
+}
+
+// CheckCollisions returns the names matched in the environment by more than
+// one variable of the default set and of the other sets, see [EnvSet.CheckCollisions].
+func CheckCollisions(others ...*EnvSet) []string {
+	return Environment.CheckCollisions(others...)
+}
+
+// Environ returns the current values of the variables in the set as a list
+// of "NAME=value" entries, in lexicographical order, in the form used by
+// [os.Environ] and [os/exec.Cmd.Env]. Variables defined with [EnvSet.Func],
+// [EnvSet.BoolFunc] and [EnvSet.TupleVar] hold no value and are left out.
+func (e *EnvSet) Environ() []string {
+	var environ []string
+	e.VisitAll(func(spec *Spec) {
+		switch spec.Value.(type) {
+		case funcValue, boolFuncValue, tupleValue:
+			return
+		}
+		environ = append(environ, e.prefix+spec.Name+"="+spec.Value.String())
+	})
+	return environ
+}
+
+// Environ returns the current values of the variables in the default set
+// as a list of "NAME=value" entries, in lexicographical order.
+func Environ() []string {
+	return Environment.Environ()
+}
+
+// acceptedNames returns the names under which the variable name can be
+// set, in priority order.
+func (e *EnvSet) acceptedNames(name string) []string {
+	if names, ok := e.names[name]; ok {
+		return names
+	}
+	return []string{name}
+}
+
+// envNames returns the accepted names of the variable name as they appear
+// in the environment, that is with the prefix of the set.
+func (e *EnvSet) envNames(name string) []string {
+	return e.prefixed(e.acceptedNames(name))
+}
+
+// defaultEnvironment is the default function to print a usage message.
+func (e *EnvSet) defaultEnvironment() {
+	if e.name == "" {
+		fmt.Fprintf(e.Output(), "Environment:\n")
+	} else {
+		fmt.Fprintf(e.Output(), "Environment of %s:\n", e.name)
+	}
+	e.PrintDefaults()
+}
+
+// BoolVar defines a bool environment variable with specified name, default value, and description string.
+// The argument p points to a bool variable in which to store the value of the
+// environment variable.
+func (e *EnvSet) BoolVar(p *bool, name string, value bool, description string) {
+	e.Var(newBoolValue(value, p), name, description)
+}
+
+// BoolVar defines a bool environment variable with specified name, default value, and description string.
+// The argument p points to a bool variable in which to store the value of
+// the environment variable.
+func BoolVar(p *bool, name string, value bool, description string) {
+	Environment.Var(newBoolValue(value, p), name, description)
+}
+
+// Bool defines a bool environment variable with specified name, default value, and description string.
+// The return value is the address of a bool variable that stores the value of
+// the environment variable.
+func (e *EnvSet) Bool(name string, value bool, description string) *bool {
+	p := new(bool)
+	e.Var(newBoolValue(value, p), name, description)
+	return p
+}
+
+// Bool defines a bool environment variable with specified name, default value, and description string.
+// The return value is the address of a bool variable that stores the value of the environment variable.
+func Bool(name string, value bool, description string) *bool {
+	return Environment.Bool(name, value, description)
+}
+
+// IntVar defines an int environment variable with specified name, default value, and description string.
+// The argument p points to an int variable in which to store the value of the environment variable.
+func (e *EnvSet) IntVar(p *int, name string, value int, description string) {
+	e.Var(newIntValue(value, p), name, description)
+}
+
+// IntVar defines an int environment variable with specified name, default value, and description string.
+// The argument p points to an int variable in which to store the value of the variable.
+func IntVar(p *int, name string, value int, description string) {
+	Environment.Var(newIntValue(value, p), name, description)
+}
+
+// Int defines an int environment variable with specified name, default value, and description string.
+// The return value is the address of an int variable that stores the value of the variable.
+func (e *EnvSet) Int(name string, value int, description string) *int {
+	p := new(int)
+	e.Var(newIntValue(value, p), name, description)
+	return p
+}
+
+// Int defines an int environment variable with specified name, default value, and description string.
+// The return value is the address of an int variable that stores the value of the variable.
+func Int(name string, value int, description string) *int {
+	return Environment.Int(name, value, description)
+}
+
+// CountVar defines a count environment variable with specified name, default value, and description string.
+// The argument p points to an int variable in which to store the value of the variable.
+// The environment variable accepts an integer, which sets the count, or the empty
+// string, which increments the count by one, so that VERBOSE= raises the verbosity
+// a level above the default as -v does for a command.
+func (e *EnvSet) CountVar(p *int, name string, value int, description string) {
+	e.Var(newCountValue(value, p), name, description)
+}
+
+// CountVar defines a count environment variable with specified name, default value, and description string.
+// The argument p points to an int variable in which to store the value of the variable.
+// The environment variable accepts an integer, which sets the count, or the empty
+// string, which increments the count by one.
+func CountVar(p *int, name string, value int, description string) {
+	Environment.Var(newCountValue(value, p), name, description)
+}
+
+// Count defines a count environment variable with specified name, default value, and description string.
+// The return value is the address of an int variable that stores the value of the variable.
+// The environment variable accepts an integer, which sets the count, or the empty
+// string, which increments the count by one, so that VERBOSE= raises the verbosity
+// a level above the default as -v does for a command.
+func (e *EnvSet) Count(name string, value int, description string) *int {
+	p := new(int)
+	e.Var(newCountValue(value, p), name, description)
+	return p
+}
+
+// Count defines a count environment variable with specified name, default value, and description string.
+// The return value is the address of an int variable that stores the value of the variable.
+// The environment variable accepts an integer, which sets the count, or the empty
+// string, which increments the count by one.
+func Count(name string, value int, description string) *int {
+	return Environment.Count(name, value, description)
+}
+
+// Int64Var defines an int64 environment variable with specified name, default value, and description string.
+// The argument p points to an int64 variable in which to store the value of the variable.
+func (e *EnvSet) Int64Var(p *int64, name string, value int64, description string) {
+	e.Var(newInt64Value(value, p), name, description)
+}
+
+// Int64Var defines an int64 environment variable with specified name, default value, and description string.
+// The argument p points to an int64 variable in which to store the value of the variable.
+func Int64Var(p *int64, name string, value int64, description string) {
+	Environment.Var(newInt64Value(value, p), name, description)
+}
+
+// Int64 defines an int64 environment variable with specified name, default value, and description string.
+// The return value is the address of an int64 variable that stores the value of the variable.
+func (e *EnvSet) Int64(name string, value int64, description string) *int64 {
+	p := new(int64)
+	e.Var(newInt64Value(value, p), name, description)
+	return p
+}
+
+// Int64 defines an int64 environment variable with specified name, default value, and description string.
+// The return value is the address of an int64 variable that stores the value of the variable.
+func Int64(name string, value int64, description string) *int64 {
+	return Environment.Int64(name, value, description)
+}
+
+// UintVar defines a uint environment variable with specified name, default value, and description string.
+// The argument p points to a uint variable in which to store the value of the variable.
+func (e *EnvSet) UintVar(p *uint, name string, value uint, description string) {
+	e.Var(newUintValue(value, p), name, description)
+}
+
+// UintVar defines a uint environment variable with specified name, default value, and description string.
+// The argument p points to a uint variable in which to store the value of the variable.
+func UintVar(p *uint, name string, value uint, description string) {
+	Environment.Var(newUintValue(value, p), name, description)
+}
+
+// Uint defines a uint environment variable with specified name, default value, and description string.
+// The return value is the address of a uint variable that stores the value of the variable.
+func (e *EnvSet) Uint(name string, value uint, description string) *uint {
+	p := new(uint)
+	e.Var(newUintValue(value, p), name, description)
+	return p
+}
+
+// Uint defines a uint environment variable with specified name, default value, and description string.
+// The return value is the address of a uint variable that stores the value of the variable.
+func Uint(name string, value uint, description string) *uint {
+	return Environment.Uint(name, value, description)
+}
+
+// Uint64Var defines a uint64 environment variable with specified name, default value, and description string.
+// The argument p points to a uint64 variable in which to store the value of the variable.
+func (e *EnvSet) Uint64Var(p *uint64, name string, value uint64, description string) {
+	e.Var(newUint64Value(value, p), name, description)
+}
+
+// Uint64Var defines a uint64 environment variable with specified name, default value, and description string.
+// The argument p points to a uint64 variable in which to store the value of the variable.
+func Uint64Var(p *uint64, name string, value uint64, description string) {
+	Environment.Var(newUint64Value(value, p), name, description)
+}
+
+// Uint64 defines a uint64 environment variable with specified name, default value, and description string.
+// The return value is the address of a uint64 variable that stores the value of the variable.
+func (e *EnvSet) Uint64(name string, value uint64, description string) *uint64 {
+	p := new(uint64)
+	e.Var(newUint64Value(value, p), name, description)
+	return p
+}
+
+// Uint64 defines a uint64 environment variable with specified name, default value, and description string.
+// The return value is the address of a uint64 variable that stores the value of the variable.
+func Uint64(name string, value uint64, description string) *uint64 {
+	return Environment.Uint64(name, value, description)
+}
+
+// StringVar defines a string environment variable with specified name, default value, and description string.
+// The argument p points to a string variable in which to store the value of the variable.
+func (e *EnvSet) StringVar(p *string, name string, value string, description string) {
+	e.Var(newStringValue(value, p), name, description)
+}
+
+// StringVar defines a string environment variable with specified name, default value, and description string.
+// The argument p points to a string variable in which to store the value of the variable.
+func StringVar(p *string, name string, value string, description string) {
+	Environment.Var(newStringValue(value, p), name, description)
+}
+
+// String defines a string environment variable with specified name, default value, and description string.
+// The return value is the address of a string variable that stores the value of the variable.
+func (e *EnvSet) String(name string, value string, description string) *string {
+	p := new(string)
+	e.Var(newStringValue(value, p), name, description)
+	return p
+}
+
+// String defines a string environment variable with specified name, default value, and description string.
+// The return value is the address of a string variable that stores the value of the variable.
+func String(name string, value string, description string) *string {
+	return Environment.String(name, value, description)
+}
+
+// Float64Var defines a float64 environment variable with specified name, default value, and description string.
+// The argument p points to a float64 variable in which to store the value of the variable.
+func (e *EnvSet) Float64Var(p *float64, name string, value float64, description string) {
+	e.Var(newFloat64Value(value, p), name, description)
+}
+
+// Float64Var defines a float64 environment variable with specified name, default value, and description string.
+// The argument p points to a float64 variable in which to store the value of the variable.
+func Float64Var(p *float64, name string, value float64, description string) {
+	Environment.Var(newFloat64Value(value, p), name, description)
+}
+
+// Float64 defines a float64 environment variable with specified name, default value, and description string.
+// The return value is the address of a float64 variable that stores the value of the variable.
+func (e *EnvSet) Float64(name string, value float64, description string) *float64 {
+	p := new(float64)
+	e.Var(newFloat64Value(value, p), name, description)
+	return p
+}
+
+// Float64 defines a float64 environment variable with specified name, default value, and description string.
+// The return value is the address of a float64 variable that stores the value of the variable.
+func Float64(name string, value float64, description string) *float64 {
+	return Environment.Float64(name, value, description)
+}
+
+// DurationVar defines a time.Duration environment variable with specified name, default value, and description string.
+// The argument p points to a time.Duration variable in which to store the value of the variable.
+// The environment variable accepts a value acceptable to time.ParseDuration.
+func (e *EnvSet) DurationVar(p *time.Duration, name string, value time.Duration, description string) {
+	e.Var(newDurationValue(value, p), name, description)
+}
+
+// DurationVar defines a time.Duration environment variable with specified name, default value, and description string.
+// The argument p points to a time.Duration variable in which to store the value of the variable.
+// The environment variable accepts a value acceptable to time.ParseDuration.
+func DurationVar(p *time.Duration, name string, value time.Duration, description string) {
+	Environment.Var(newDurationValue(value, p), name, description)
+}
+
+// Duration defines a time.Duration environment variable with specified name, default value, and description string.
+// The return value is the address of a time.Duration variable that stores the value of the variable.
+// The environment variable accepts a value acceptable to time.ParseDuration.
+func (e *EnvSet) Duration(name string, value time.Duration, description string) *time.Duration {
+	p := new(time.Duration)
+	e.Var(newDurationValue(value, p), name, description)
+	return p
+}
+
+// Duration defines a time.Duration environment variable with specified name, default value, and description string.
+// The return value is the address of a time.Duration variable that stores the value of the variable.
+// The environment variable accepts a value acceptable to time.ParseDuration.
+func Duration(name string, value time.Duration, description string) *time.Duration {
+	return Environment.Duration(name, value, description)
+}
+
+// NamedDurationVar defines a time.Duration environment variable with specified name, default value, presets, and description string.
+// The argument p points to a time.Duration variable in which to store the value of the variable.
+// The environment variable accepts either the name of one of the presets, in any case,
+// or a value acceptable to time.ParseDuration.
+func (e *EnvSet) NamedDurationVar(p *time.Duration, name string, value time.Duration, presets map[string]time.Duration, description string) {
+	e.Var(newNamedDurationValue(value, p, presets), name, description)
+}
+
+// NamedDurationVar defines a time.Duration environment variable with specified name, default value, presets, and description string.
+// The argument p points to a time.Duration variable in which to store the value of the variable.
+// The environment variable accepts either the name of one of the presets, in any case,
+// or a value acceptable to time.ParseDuration.
+func NamedDurationVar(p *time.Duration, name string, value time.Duration, presets map[string]time.Duration, description string) {
+	Environment.Var(newNamedDurationValue(value, p, presets), name, description)
+}
+
+// NamedDuration defines a time.Duration environment variable with specified name, default value, presets, and description string.
+// The return value is the address of a time.Duration variable that stores the value of the variable.
+// The environment variable accepts either the name of one of the presets, in any case,
+// or a value acceptable to time.ParseDuration.
+func (e *EnvSet) NamedDuration(name string, value time.Duration, presets map[string]time.Duration, description string) *time.Duration {
+	p := new(time.Duration)
+	e.Var(newNamedDurationValue(value, p, presets), name, description)
+	return p
+}
+
+// NamedDuration defines a time.Duration environment variable with specified name, default value, presets, and description string.
+// The return value is the address of a time.Duration variable that stores the value of the variable.
+// The environment variable accepts either the name of one of the presets, in any case,
+// or a value acceptable to time.ParseDuration.
+func NamedDuration(name string, value time.Duration, presets map[string]time.Duration, description string) *time.Duration {
+	return Environment.NamedDuration(name, value, presets, description)
+}
+
+// DurationRangeVar defines a time.Duration environment variable with specified name, default value, bounds, and description string.
+// The argument p points to a time.Duration variable in which to store the value of the variable.
+// The environment variable accepts a value acceptable to time.ParseDuration between min and max, inclusive.
+// The default value must be within the bounds too.
+func (e *EnvSet) DurationRangeVar(p *time.Duration, name string, value, min, max time.Duration, description string) {
+	v, err := newDurationRangeValue(value, p, min, max)
+	e.checkDefault(name, err)
+	e.Var(v, name, description)
+}
+
+// DurationRangeVar defines a time.Duration environment variable with specified name, default value, bounds, and description string.
+// The argument p points to a time.Duration variable in which to store the value of the variable.
+// The environment variable accepts a value acceptable to time.ParseDuration between min and max, inclusive.
+// The default value must be within the bounds too.
+func DurationRangeVar(p *time.Duration, name string, value, min, max time.Duration, description string) {
+	v, err := newDurationRangeValue(value, p, min, max)
+	Environment.checkDefault(name, err)
+	Environment.Var(v, name, description)
+}
+
+// DurationRange defines a time.Duration environment variable with specified name, default value, bounds, and description string.
+// The return value is the address of a time.Duration variable that stores the value of the variable.
+// The environment variable accepts a value acceptable to time.ParseDuration between min and max, inclusive.
+// The default value must be within the bounds too.
+func (e *EnvSet) DurationRange(name string, value, min, max time.Duration, description string) *time.Duration {
+	p := new(time.Duration)
+	v, err := newDurationRangeValue(value, p, min, max)
+	e.checkDefault(name, err)
+	e.Var(v, name, description)
+	return p
+}
+
+// DurationRange defines a time.Duration environment variable with specified name, default value, bounds, and description string.
+// The return value is the address of a time.Duration variable that stores the value of the variable.
+// The environment variable accepts a value acceptable to time.ParseDuration between min and max, inclusive.
+// The default value must be within the bounds too.
+func DurationRange(name string, value, min, max time.Duration, description string) *time.Duration {
+	return Environment.DurationRange(name, value, min, max, description)
+}
+
+// BackoffScheduleVar defines a []time.Duration environment variable with specified name, default value, and description string.
+// The argument p points to a []time.Duration variable in which to store the value of the variable.
+// The environment variable accepts a comma-separated list of durations, such as "1s,+2s,+4s",
+// where a duration with a leading + is added to the previous one, giving [1s 3s 7s].
+func (e *EnvSet) BackoffScheduleVar(p *[]time.Duration, name string, value []time.Duration, description string) {
+	e.Var(newScheduleValue(value, p), name, description)
+}
+
+// BackoffScheduleVar defines a []time.Duration environment variable with specified name, default value, and description string.
+// The argument p points to a []time.Duration variable in which to store the value of the variable.
+// The environment variable accepts a comma-separated list of durations, such as "1s,+2s,+4s",
+// where a duration with a leading + is added to the previous one, giving [1s 3s 7s].
+func BackoffScheduleVar(p *[]time.Duration, name string, value []time.Duration, description string) {
+	Environment.Var(newScheduleValue(value, p), name, description)
+}
+
+// BackoffSchedule defines a []time.Duration environment variable with specified name, default value, and description string.
+// The return value is the address of a []time.Duration variable that stores the value of the variable.
+// The environment variable accepts a comma-separated list of durations, such as "1s,+2s,+4s",
+// where a duration with a leading + is added to the previous one, giving [1s 3s 7s].
+func (e *EnvSet) BackoffSchedule(name string, value []time.Duration, description string) *[]time.Duration {
+	p := new([]time.Duration)
+	e.BackoffScheduleVar(p, name, value, description)
+	return p
+}
+
+// BackoffSchedule defines a []time.Duration environment variable with specified name, default value, and description string.
+// The return value is the address of a []time.Duration variable that stores the value of the variable.
+// The environment variable accepts a comma-separated list of durations, such as "1s,+2s,+4s",
+// where a duration with a leading + is added to the previous one, giving [1s 3s 7s].
+func BackoffSchedule(name string, value []time.Duration, description string) *[]time.Duration {
+	return Environment.BackoffSchedule(name, value, description)
+}
+
+// SignedDurationVar defines a time.Duration environment variable with specified name, default value, sign policy, and description string.
+// The argument p points to a time.Duration variable in which to store the value of the variable.
+// The environment variable accepts a value acceptable to time.ParseDuration, such as "-5m",
+// whose sign is allowed by policy. The default value must be allowed by policy too.
+func (e *EnvSet) SignedDurationVar(p *time.Duration, name string, value time.Duration, policy SignPolicy, description string) {
+	v, err := newSignedDurationValue(value, p, policy)
+	e.checkDefault(name, err)
+	e.Var(v, name, description)
+}
+
+// SignedDurationVar defines a time.Duration environment variable with specified name, default value, sign policy, and description string.
+// The argument p points to a time.Duration variable in which to store the value of the variable.
+// The environment variable accepts a value acceptable to time.ParseDuration, such as "-5m",
+// whose sign is allowed by policy. The default value must be allowed by policy too.
+func SignedDurationVar(p *time.Duration, name string, value time.Duration, policy SignPolicy, description string) {
+	v, err := newSignedDurationValue(value, p, policy)
+	Environment.checkDefault(name, err)
+	Environment.Var(v, name, description)
+}
+
+// SignedDuration defines a time.Duration environment variable with specified name, default value, sign policy, and description string.
+// The return value is the address of a time.Duration variable that stores the value of the variable.
+// The environment variable accepts a value acceptable to time.ParseDuration, such as "-5m",
+// whose sign is allowed by policy. The default value must be allowed by policy too.
+func (e *EnvSet) SignedDuration(name string, value time.Duration, policy SignPolicy, description string) *time.Duration {
+	p := new(time.Duration)
+	v, err := newSignedDurationValue(value, p, policy)
+	e.checkDefault(name, err)
+	e.Var(v, name, description)
+	return p
+}
+
+// SignedDuration defines a time.Duration environment variable with specified name, default value, sign policy, and description string.
+// The return value is the address of a time.Duration variable that stores the value of the variable.
+// The environment variable accepts a value acceptable to time.ParseDuration, such as "-5m",
+// whose sign is allowed by policy. The default value must be allowed by policy too.
+func SignedDuration(name string, value time.Duration, policy SignPolicy, description string) *time.Duration {
+	return Environment.SignedDuration(name, value, policy, description)
+}
+
+// PercentVar defines a float64 environment variable with specified name, default value, and description string.
+// The argument p points to a float64 variable in which to store the value of the variable.
+// The environment variable accepts either a percentage, such as "50%", or a fraction, such as "0.5",
+// and stores the fraction. Values outside of [0, 1] are rejected.
+func (e *EnvSet) PercentVar(p *float64, name string, value float64, description string) {
+	e.Var(newPercentValue(value, p), name, description)
+}
+
+// PercentVar defines a float64 environment variable with specified name, default value, and description string.
+// The argument p points to a float64 variable in which to store the value of the variable.
+// The environment variable accepts either a percentage, such as "50%", or a fraction, such as "0.5",
+// and stores the fraction. Values outside of [0, 1] are rejected.
+func PercentVar(p *float64, name string, value float64, description string) {
+	Environment.Var(newPercentValue(value, p), name, description)
+}
+
+// Percent defines a float64 environment variable with specified name, default value, and description string.
+// The return value is the address of a float64 variable that stores the value of the variable.
+// The environment variable accepts either a percentage, such as "50%", or a fraction, such as "0.5",
+// and stores the fraction. Values outside of [0, 1] are rejected.
+func (e *EnvSet) Percent(name string, value float64, description string) *float64 {
+	p := new(float64)
+	e.Var(newPercentValue(value, p), name, description)
+	return p
+}
+
+// Percent defines a float64 environment variable with specified name, default value, and description string.
+// The return value is the address of a float64 variable that stores the value of the variable.
+// The environment variable accepts either a percentage, such as "50%", or a fraction, such as "0.5",
+// and stores the fraction. Values outside of [0, 1] are rejected.
+func Percent(name string, value float64, description string) *float64 {
+	return Environment.Percent(name, value, description)
+}
+
+// UnitVar defines a float64 environment variable with specified name, default value, and description string.
+// The argument p points to a float64 variable in which to store the value of the variable.
+// The environment variable accepts a number followed by one of the units, such as "250ms"
+// for the units {"s": 1, "ms": 0.001}, and stores the number multiplied by the unit's factor.
+// A number with no unit is stored as is.
+func (e *EnvSet) UnitVar(p *float64, name string, value float64, units map[string]float64, description string) {
+	e.Var(newUnitValue(value, p, units), name, description)
+}
+
+// UnitVar defines a float64 environment variable with specified name, default value, and description string.
+// The argument p points to a float64 variable in which to store the value of the variable.
+// The environment variable accepts a number followed by one of the units, such as "250ms"
+// for the units {"s": 1, "ms": 0.001}, and stores the number multiplied by the unit's factor.
+// A number with no unit is stored as is.
+func UnitVar(p *float64, name string, value float64, units map[string]float64, description string) {
+	Environment.Var(newUnitValue(value, p, units), name, description)
+}
+
+// Unit defines a float64 environment variable with specified name, default value, and description string.
+// The return value is the address of a float64 variable that stores the value of the variable.
+// The environment variable accepts a number followed by one of the units, such as "250ms"
+// for the units {"s": 1, "ms": 0.001}, and stores the number multiplied by the unit's factor.
+// A number with no unit is stored as is.
+func (e *EnvSet) Unit(name string, value float64, units map[string]float64, description string) *float64 {
+	p := new(float64)
+	e.Var(newUnitValue(value, p, units), name, description)
+	return p
+}
+
+// Unit defines a float64 environment variable with specified name, default value, and description string.
+// The return value is the address of a float64 variable that stores the value of the variable.
+// The environment variable accepts a number followed by one of the units, such as "250ms"
+// for the units {"s": 1, "ms": 0.001}, and stores the number multiplied by the unit's factor.
+// A number with no unit is stored as is.
+func Unit(name string, value float64, units map[string]float64, description string) *float64 {
+	return Environment.Unit(name, value, units, description)
+}
+
+// RolloutVar defines a float64 environment variable with specified name, default value, and description string.
+// The argument p points to a float64 variable in which to store the value of the variable.
+// The environment variable accepts either a boolean, such as "on" or "false", for a fraction of 1 or 0,
+// or a percentage, such as "25%", or a fraction, such as "0.25", and stores the fraction.
+// Values outside of [0, 1] are rejected.
+func (e *EnvSet) RolloutVar(p *float64, name string, value float64, description string) {
+	e.Var(newRolloutValue(value, p), name, description)
+}
+
+// RolloutVar defines a float64 environment variable with specified name, default value, and description string.
+// The argument p points to a float64 variable in which to store the value of the variable.
+// The environment variable accepts either a boolean, such as "on" or "false", for a fraction of 1 or 0,
+// or a percentage, such as "25%", or a fraction, such as "0.25", and stores the fraction.
+// Values outside of [0, 1] are rejected.
+func RolloutVar(p *float64, name string, value float64, description string) {
+	Environment.Var(newRolloutValue(value, p), name, description)
+}
+
+// Rollout defines a float64 environment variable with specified name, default value, and description string.
+// The return value is the address of a float64 variable that stores the value of the variable.
+// The environment variable accepts either a boolean, such as "on" or "false", for a fraction of 1 or 0,
+// or a percentage, such as "25%", or a fraction, such as "0.25", and stores the fraction.
+// Values outside of [0, 1] are rejected.
+func (e *EnvSet) Rollout(name string, value float64, description string) *float64 {
+	p := new(float64)
+	e.Var(newRolloutValue(value, p), name, description)
+	return p
+}
+
+// Rollout defines a float64 environment variable with specified name, default value, and description string.
+// The return value is the address of a float64 variable that stores the value of the variable.
+// The environment variable accepts either a boolean, such as "on" or "false", for a fraction of 1 or 0,
+// or a percentage, such as "25%", or a fraction, such as "0.25", and stores the fraction.
+// Values outside of [0, 1] are rejected.
+func Rollout(name string, value float64, description string) *float64 {
+	return Environment.Rollout(name, value, description)
+}
+
+// BasisPointsVar defines an int environment variable with specified name, default value, and description string.
+// The argument p points to an int variable in which to store the value of the variable.
+// The environment variable accepts a percentage, such as "2.5%" or "2.5", and stores it
+// in basis points, 250, rounded to the nearest integer. Values outside of [0%, 100%] are rejected.
+func (e *EnvSet) BasisPointsVar(p *int, name string, value int, description string) {
+	v, err := newBasisPointsValue(value, p, 0, 10000)
+	e.checkDefault(name, err)
+	e.Var(v, name, description)
+}
+
+// BasisPointsVar defines an int environment variable with specified name, default value, and description string.
+// The argument p points to an int variable in which to store the value of the variable.
+// The environment variable accepts a percentage, such as "2.5%" or "2.5", and stores it
+// in basis points, 250, rounded to the nearest integer. Values outside of [0%, 100%] are rejected.
+func BasisPointsVar(p *int, name string, value int, description string) {
+	v, err := newBasisPointsValue(value, p, 0, 10000)
+	Environment.checkDefault(name, err)
+	Environment.Var(v, name, description)
+}
+
+// BasisPoints defines an int environment variable with specified name, default value, and description string.
+// The return value is the address of an int variable that stores the value of the variable.
+// The environment variable accepts a percentage, such as "2.5%" or "2.5", and stores it
+// in basis points, 250, rounded to the nearest integer. Values outside of [0%, 100%] are rejected.
+func (e *EnvSet) BasisPoints(name string, value int, description string) *int {
+	p := new(int)
+	v, err := newBasisPointsValue(value, p, 0, 10000)
+	e.checkDefault(name, err)
+	e.Var(v, name, description)
+	return p
+}
+
+// BasisPoints defines an int environment variable with specified name, default value, and description string.
+// The return value is the address of an int variable that stores the value of the variable.
+// The environment variable accepts a percentage, such as "2.5%" or "2.5", and stores it
+// in basis points, 250, rounded to the nearest integer. Values outside of [0%, 100%] are rejected.
+func BasisPoints(name string, value int, description string) *int {
+	return Environment.BasisPoints(name, value, description)
+}
+
+// BasisPointsRangeVar defines an int environment variable with specified name, default value, bounds, and description string.
+// The argument p points to an int variable in which to store the value of the variable.
+// The environment variable accepts a percentage, such as "2.5%" or "2.5", and stores it
+// in basis points, 250, rounded to the nearest integer.
+// Values outside of [min, max], in basis points, are rejected. The default value must be within the bounds too.
+func (e *EnvSet) BasisPointsRangeVar(p *int, name string, value, min, max int, description string) {
+	v, err := newBasisPointsValue(value, p, min, max)
+	e.checkDefault(name, err)
+	e.Var(v, name, description)
+}
+
+// BasisPointsRangeVar defines an int environment variable with specified name, default value, bounds, and description string.
+// The argument p points to an int variable in which to store the value of the variable.
+// The environment variable accepts a percentage, such as "2.5%" or "2.5", and stores it
+// in basis points, 250, rounded to the nearest integer.
+// Values outside of [min, max], in basis points, are rejected. The default value must be within the bounds too.
+func BasisPointsRangeVar(p *int, name string, value, min, max int, description string) {
+	v, err := newBasisPointsValue(value, p, min, max)
+	Environment.checkDefault(name, err)
+	Environment.Var(v, name, description)
+}
+
+// StringSliceVar defines a []string environment variable with specified name, default value, and description string.
+// The argument p points to a []string variable in which to store the value of the variable.
+// The environment variable is split on commas, such as "a.com,b.com"; an empty value yields an empty slice.
+// A comma preceded by a backslash is part of an element, and two backslashes stand for one.
+func (e *EnvSet) StringSliceVar(p *[]string, name string, value []string, description string) {
+	e.Var(newStringSliceValue(value, p, false), name, description)
+}
+
+// StringSliceVar defines a []string environment variable with specified name, default value, and description string.
+// The argument p points to a []string variable in which to store the value of the variable.
+// The environment variable is split on commas, such as "a.com,b.com"; an empty value yields an empty slice.
+// A comma preceded by a backslash is part of an element, and two backslashes stand for one.
+func StringSliceVar(p *[]string, name string, value []string, description string) {
+	Environment.Var(newStringSliceValue(value, p, false), name, description)
+}
+
+// StringSlice defines a []string environment variable with specified name, default value, and description string.
+// The return value is the address of a []string variable that stores the value of the variable.
+// The environment variable is split on commas, such as "a.com,b.com"; an empty value yields an empty slice.
+// A comma preceded by a backslash is part of an element, and two backslashes stand for one.
+func (e *EnvSet) StringSlice(name string, value []string, description string) *[]string {
+	p := new([]string)
+	e.Var(newStringSliceValue(value, p, false), name, description)
+	return p
+}
+
+// StringSlice defines a []string environment variable with specified name, default value, and description string.
+// The return value is the address of a []string variable that stores the value of the variable.
+// The environment variable is split on commas, such as "a.com,b.com"; an empty value yields an empty slice.
+// A comma preceded by a backslash is part of an element, and two backslashes stand for one.
+func StringSlice(name string, value []string, description string) *[]string {
+	return Environment.StringSlice(name, value, description)
+}
+
+// NewStringSliceValue returns a [Value] that stores a comma-separated list into p, as
+// [EnvSet.StringSliceVar] does, for use with [EnvSet.Var]; p is initialized to value.
+// If trim is set, the white space around each element is removed, so that "a, b" holds "a" and "b".
+func NewStringSliceValue(p *[]string, value []string, trim bool) Value {
+	return newStringSliceValue(value, p, trim)
+}
+
+// JSONSliceVar defines a []string environment variable with specified name, default value, and description string.
+// The argument p points to a []string variable in which to store the value of the variable.
+// The environment variable accepts a JSON array of strings, such as ["a","b"].
+func (e *EnvSet) JSONSliceVar(p *[]string, name string, value []string, description string) {
+	e.Var(newJSONSliceValue(value, p), name, description)
+}
+
+// JSONSliceVar defines a []string environment variable with specified name, default value, and description string.
+// The argument p points to a []string variable in which to store the value of the variable.
+// The environment variable accepts a JSON array of strings, such as ["a","b"].
+func JSONSliceVar(p *[]string, name string, value []string, description string) {
+	Environment.Var(newJSONSliceValue(value, p), name, description)
+}
+
+// JSONSlice defines a []string environment variable with specified name, default value, and description string.
+// The return value is the address of a []string variable that stores the value of the variable.
+// The environment variable accepts a JSON array of strings, such as ["a","b"].
+func (e *EnvSet) JSONSlice(name string, value []string, description string) *[]string {
+	p := new([]string)
+	e.Var(newJSONSliceValue(value, p), name, description)
+	return p
+}
+
+// JSONSlice defines a []string environment variable with specified name, default value, and description string.
+// The return value is the address of a []string variable that stores the value of the variable.
+// The environment variable accepts a JSON array of strings, such as ["a","b"].
+func JSONSlice(name string, value []string, description string) *[]string {
+	return Environment.JSONSlice(name, value, description)
+}
+
+// NewJSONSliceValue returns a [Value] that stores a JSON array into the slice p,
+// for use with [EnvSet.Var]. The elements of the array are decoded as by [json.Unmarshal]
+// and the slice is initialized to value.
+func NewJSONSliceValue[T any](p *[]T, value []T) Value {
+	return newJSONSliceValue(value, p)
+}
+
+// UnixTimeVar defines a time.Time environment variable with specified name, default value, and description string.
+// The argument p points to a time.Time variable in which to store the value of the variable.
+// The environment variable accepts a Unix timestamp in seconds, which may be negative.
+func (e *EnvSet) UnixTimeVar(p *time.Time, name string, value time.Time, description string) {
+	e.Var(newUnixTimeValue(value, p, false), name, description)
+}
+
+// UnixTimeVar defines a time.Time environment variable with specified name, default value, and description string.
+// The argument p points to a time.Time variable in which to store the value of the variable.
+// The environment variable accepts a Unix timestamp in seconds, which may be negative.
+func UnixTimeVar(p *time.Time, name string, value time.Time, description string) {
+	Environment.Var(newUnixTimeValue(value, p, false), name, description)
+}
+
+// UnixTime defines a time.Time environment variable with specified name, default value, and description string.
+// The return value is the address of a time.Time variable that stores the value of the variable.
+// The environment variable accepts a Unix timestamp in seconds, which may be negative.
+func (e *EnvSet) UnixTime(name string, value time.Time, description string) *time.Time {
+	p := new(time.Time)
+	e.Var(newUnixTimeValue(value, p, false), name, description)
+	return p
+}
+
+// UnixTime defines a time.Time environment variable with specified name, default value, and description string.
+// The return value is the address of a time.Time variable that stores the value of the variable.
+// The environment variable accepts a Unix timestamp in seconds, which may be negative.
+func UnixTime(name string, value time.Time, description string) *time.Time {
+	return Environment.UnixTime(name, value, description)
+}
+
+// UnixMilliTimeVar defines a time.Time environment variable with specified name, default value, and description string.
+// The argument p points to a time.Time variable in which to store the value of the variable.
+// The environment variable accepts a Unix timestamp in milliseconds, which may be negative.
+func (e *EnvSet) UnixMilliTimeVar(p *time.Time, name string, value time.Time, description string) {
+	e.Var(newUnixTimeValue(value, p, true), name, description)
+}
+
+// UnixMilliTimeVar defines a time.Time environment variable with specified name, default value, and description string.
+// The argument p points to a time.Time variable in which to store the value of the variable.
+// The environment variable accepts a Unix timestamp in milliseconds, which may be negative.
+func UnixMilliTimeVar(p *time.Time, name string, value time.Time, description string) {
+	Environment.Var(newUnixTimeValue(value, p, true), name, description)
+}
+
+// UnixMilliTime defines a time.Time environment variable with specified name, default value, and description string.
+// The return value is the address of a time.Time variable that stores the value of the variable.
+// The environment variable accepts a Unix timestamp in milliseconds, which may be negative.
+func (e *EnvSet) UnixMilliTime(name string, value time.Time, description string) *time.Time {
+	p := new(time.Time)
+	e.Var(newUnixTimeValue(value, p, true), name, description)
+	return p
+}
+
+// UnixMilliTime defines a time.Time environment variable with specified name, default value, and description string.
+// The return value is the address of a time.Time variable that stores the value of the variable.
+// The environment variable accepts a Unix timestamp in milliseconds, which may be negative.
+func UnixMilliTime(name string, value time.Time, description string) *time.Time {
+	return Environment.UnixMilliTime(name, value, description)
+}
+
+// TimeVar defines a time.Time environment variable with specified name, default value, layout, and description string.
+// The argument p points to a time.Time variable in which to store the value of the variable.
+// The environment variable accepts a time in the given layout, as understood by time.Parse,
+// or in RFC 3339 if the layout is empty.
+func (e *EnvSet) TimeVar(p *time.Time, name string, value time.Time, layout, description string) {
+	e.Var(newTimeValue(value, p, layout), name, description)
+}
+
+// TimeVar defines a time.Time environment variable with specified name, default value, layout, and description string.
+// The argument p points to a time.Time variable in which to store the value of the variable.
+// The environment variable accepts a time in the given layout, as understood by time.Parse,
+// or in RFC 3339 if the layout is empty.
+func TimeVar(p *time.Time, name string, value time.Time, layout, description string) {
+	Environment.Var(newTimeValue(value, p, layout), name, description)
+}
+
+// Time defines a time.Time environment variable with specified name, default value, layout, and description string.
+// The return value is the address of a time.Time variable that stores the value of the variable.
+// The environment variable accepts a time in the given layout, as understood by time.Parse,
+// or in RFC 3339 if the layout is empty.
+func (e *EnvSet) Time(name string, value time.Time, layout, description string) *time.Time {
+	p := new(time.Time)
+	e.TimeVar(p, name, value, layout, description)
+	return p
+}
+
+// Time defines a time.Time environment variable with specified name, default value, layout, and description string.
+// The return value is the address of a time.Time variable that stores the value of the variable.
+// The environment variable accepts a time in the given layout, as understood by time.Parse,
+// or in RFC 3339 if the layout is empty.
+func Time(name string, value time.Time, layout, description string) *time.Time {
+	return Environment.Time(name, value, layout, description)
+}
+
+// NewSliceValue returns a [Value] that stores a list of elements into the slice p,
+// for use with [EnvSet.Var]. The environment variable is split on sep and each element
+// is parsed with parse; the slice is initialized to value.
+// The elements are formatted back with [fmt.Sprint]. NewSliceValue panics if sep is empty.
+func NewSliceValue[T any](p *[]T, value []T, sep string, parse func(string) (T, error)) Value {
+	return newSliceValue(value, p, sep, parse)
+}
+
+// ValidatedStringsVar defines a []string environment variable with specified name, default value, and description string.
+// The argument p points to a []string variable in which to store the value of the variable.
+// The environment variable is split on sep and each element is checked with validate;
+// the value is rejected at the first element for which validate fails, giving its position and error.
+func (e *EnvSet) ValidatedStringsVar(p *[]string, name string, value []string, sep string, validate func(string) error, description string) {
+	v := newSliceValue(value, p, sep, validated(validate))
+	v.first = true
+	e.Var(v, name, description)
+}
+
+// ValidatedStringsVar defines a []string environment variable with specified name, default value, and description string.
+// The argument p points to a []string variable in which to store the value of the variable.
+// The environment variable is split on sep and each element is checked with validate;
+// the value is rejected at the first element for which validate fails, giving its position and error.
+func ValidatedStringsVar(p *[]string, name string, value []string, sep string, validate func(string) error, description string) {
+	Environment.ValidatedStringsVar(p, name, value, sep, validate, description)
+}
+
+// EnumsVar defines a []string environment variable with specified name, default value, and description string.
+// The argument p points to a []string variable in which to store the value of the variable.
+// The environment variable is split on sep and each element must be one of allowed, such as
+// "auth,cache" with sep ",". The elements of the default value must be allowed too.
+func (e *EnvSet) EnumsVar(p *[]string, name string, value []string, allowed []string, sep string, description string) {
+	v, err := newEnumsValue(value, p, allowed, sep)
+	e.checkDefault(name, err)
+	e.Var(v, name, description)
+}
+
+// EnumsVar defines a []string environment variable with specified name, default value, and description string.
+// The argument p points to a []string variable in which to store the value of the variable.
+// The environment variable is split on sep and each element must be one of allowed, such as
+// "auth,cache" with sep ",". The elements of the default value must be allowed too.
+func EnumsVar(p *[]string, name string, value []string, allowed []string, sep string, description string) {
+	v, err := newEnumsValue(value, p, allowed, sep)
+	Environment.checkDefault(name, err)
+	Environment.Var(v, name, description)
+}
+
+// newEnumsValue returns the Value of a list of strings from allowed.
+func newEnumsValue(val []string, p *[]string, allowed []string, sep string) (Value, error) {
+	validate := func(s string) error {
+		if !slices.Contains(allowed, s) {
+			return fmt.Errorf("%w: not one of %s", errParse, strings.Join(allowed, ", "))
+		}
+		return nil
+	}
+	for _, elem := range val {
+		if err := validate(elem); err != nil {
+			return nil, fmt.Errorf("default element %q: %v", elem, err)
+		}
+	}
+	return &enumsValue{newSliceValue(val, p, sep, validated(validate)), allowed}, nil
+}
+
+// -- enumsValue
+type enumsValue struct {
+	*sliceValue[string]
+	allowed []string
+}
+
+func (v *enumsValue) choices() []string { return v.allowed }
+
+func (v *enumsValue) unwrap() Value { return v.sliceValue }
+
+// validated returns a parse function for a slice of strings that checks
+// each element with validate.
+func validated(validate func(string) error) func(string) (string, error) {
+	return func(s string) (string, error) {
+		return s, validate(s)
+	}
+}
+
+// SliceVar defines a []T environment variable with specified name, default value, and description string.
+// The argument p points to a []T variable in which to store the value of the variable.
+// The environment variable is split on sep and each element is parsed with parse.
+// An empty value yields an empty slice. A separator preceded by a backslash is part
+// of an element, and two backslashes stand for one: "a\,b,c" holds "a,b" and "c".
+// SliceVar panics if sep is empty.
+func SliceVar[T any](p *[]T, name string, value []T, sep string, parse func(string) (T, error), description string) {
+	Environment.Var(newSliceValue(value, p, sep, parse), name, description)
+}
+
+// NewStructSliceValue returns a [Value] that stores a list of records into the slice p,
+// for use with [EnvSet.Var]. See [StructSliceVar] for the format; the slice is initialized to value.
+func NewStructSliceValue[T any](p *[]T, value []T, recordSep, fieldSep, kvSep string) Value {
+	return newStructSliceValue(value, p, recordSep, fieldSep, kvSep)
+}
+
+// StructSliceVar defines a []T environment variable with specified name, default value, and description string,
+// where T is a struct type. The argument p points to a []T variable in which to store the value of the variable.
+// The environment variable is split in records on recordSep, each record is split in fields on fieldSep
+// and each field is a key and a value separated by kvSep. The key selects the field of T with the same `env`
+// tag and the value is parsed by the [Value] registered for the type of the field with [RegisterType].
+// For instance, given
+//
+//	type Route struct {
+//		Host   string `env:"host"`
+//		Weight int    `env:"weight"`
+//	}
+//
+// the value "host=a;weight=1|host=b;weight=2" with separators "|", ";" and "=" holds two routes.
+// StructSliceVar panics if a tagged field has a type with no registered Value.
+func StructSliceVar[T any](p *[]T, name string, value []T, recordSep, fieldSep, kvSep string, description string) {
+	Environment.Var(newStructSliceValue(value, p, recordSep, fieldSep, kvSep), name, description)
+}
+
+// AddrPortsVar defines a []netip.AddrPort environment variable with specified name, default value, and description string.
+// The argument p points to a []netip.AddrPort variable in which to store the value of the variable.
+// The environment variable is split on sep and each element is parsed with netip.ParseAddrPort,
+// such as "10.0.0.1:7946,10.0.0.2:7946" with sep ",". An empty value yields an empty slice.
+// The value is rejected at the first element that is not a valid endpoint.
+func (e *EnvSet) AddrPortsVar(p *[]netip.AddrPort, name string, value []netip.AddrPort, sep string, description string) {
+	v := newSliceValue(value, p, sep, netip.ParseAddrPort)
+	v.first = true
+	e.Var(v, name, description)
+}
+
+// AddrPortsVar defines a []netip.AddrPort environment variable with specified name, default value, and description string.
+// The argument p points to a []netip.AddrPort variable in which to store the value of the variable.
+// The environment variable is split on sep and each element is parsed with netip.ParseAddrPort,
+// such as "10.0.0.1:7946,10.0.0.2:7946" with sep ",". An empty value yields an empty slice.
+// The value is rejected at the first element that is not a valid endpoint.
+func AddrPortsVar(p *[]netip.AddrPort, name string, value []netip.AddrPort, sep string, description string) {
+	Environment.AddrPortsVar(p, name, value, sep, description)
+}
+
+// AddrPorts defines a []netip.AddrPort environment variable with specified name, default value, and description string.
+// The return value is the address of a []netip.AddrPort variable that stores the value of the variable.
+// The environment variable is split on sep and each element is parsed with netip.ParseAddrPort,
+// such as "10.0.0.1:7946,10.0.0.2:7946" with sep ",". An empty value yields an empty slice.
+// The value is rejected at the first element that is not a valid endpoint.
+func (e *EnvSet) AddrPorts(name string, value []netip.AddrPort, sep string, description string) *[]netip.AddrPort {
+	p := new([]netip.AddrPort)
+	e.AddrPortsVar(p, name, value, sep, description)
+	return p
+}
+
+// AddrPorts defines a []netip.AddrPort environment variable with specified name, default value, and description string.
+// The return value is the address of a []netip.AddrPort variable that stores the value of the variable.
+// The environment variable is split on sep and each element is parsed with netip.ParseAddrPort,
+// such as "10.0.0.1:7946,10.0.0.2:7946" with sep ",". An empty value yields an empty slice.
+// The value is rejected at the first element that is not a valid endpoint.
+func AddrPorts(name string, value []netip.AddrPort, sep string, description string) *[]netip.AddrPort {
+	return Environment.AddrPorts(name, value, sep, description)
+}
+
+// RegexpMatchVar defines a string environment variable with specified name, default value, pattern, and description string.
 // The argument p points to a string variable in which to store the value of the variable.
-func (e *EnvSet) StringVar(p *string, name string, value string, description string) {
+// The environment variable accepts a value that matches pattern, as reported by its MatchString method,
+// such as "v1.2.3" for `^v\d+\.\d+\.\d+$`. The default value must match pattern too.
+func (e *EnvSet) RegexpMatchVar(p *string, name string, value string, pattern *regexp.Regexp, description string) {
+	v, err := newRegexpMatchValue(value, p, pattern)
+	e.checkDefault(name, err)
+	e.Var(v, name, description)
+}
+
+// RegexpMatchVar defines a string environment variable with specified name, default value, pattern, and description string.
+// The argument p points to a string variable in which to store the value of the variable.
+// The environment variable accepts a value that matches pattern, as reported by its MatchString method,
+// such as "v1.2.3" for `^v\d+\.\d+\.\d+$`. The default value must match pattern too.
+func RegexpMatchVar(p *string, name string, value string, pattern *regexp.Regexp, description string) {
+	v, err := newRegexpMatchValue(value, p, pattern)
+	Environment.checkDefault(name, err)
+	Environment.Var(v, name, description)
+}
+
+// RegexpMatch defines a string environment variable with specified name, default value, pattern, and description string.
+// The return value is the address of a string variable that stores the value of the variable.
+// The environment variable accepts a value that matches pattern, as reported by its MatchString method,
+// such as "v1.2.3" for `^v\d+\.\d+\.\d+$`. The default value must match pattern too.
+func (e *EnvSet) RegexpMatch(name string, value string, pattern *regexp.Regexp, description string) *string {
+	p := new(string)
+	v, err := newRegexpMatchValue(value, p, pattern)
+	e.checkDefault(name, err)
+	e.Var(v, name, description)
+	return p
+}
+
+// RegexpMatch defines a string environment variable with specified name, default value, pattern, and description string.
+// The return value is the address of a string variable that stores the value of the variable.
+// The environment variable accepts a value that matches pattern, as reported by its MatchString method,
+// such as "v1.2.3" for `^v\d+\.\d+\.\d+$`. The default value must match pattern too.
+func RegexpMatch(name string, value string, pattern *regexp.Regexp, description string) *string {
+	return Environment.RegexpMatch(name, value, pattern, description)
+}
+
+// PathVar defines a string environment variable with specified name, default value, check mode, and description string.
+// The argument p points to a string variable in which to store the value of the variable.
+// The environment variable accepts a path that is checked, when the variable is set, according to mode.
+// The default value is not checked.
+func (e *EnvSet) PathVar(p *string, name string, value string, mode PathMode, description string) {
+	e.Var(newPathValue(value, p, mode), name, description)
+}
+
+// PathVar defines a string environment variable with specified name, default value, check mode, and description string.
+// The argument p points to a string variable in which to store the value of the variable.
+// The environment variable accepts a path that is checked, when the variable is set, according to mode.
+// The default value is not checked.
+func PathVar(p *string, name string, value string, mode PathMode, description string) {
+	Environment.Var(newPathValue(value, p, mode), name, description)
+}
+
+// Path defines a string environment variable with specified name, default value, check mode, and description string.
+// The return value is the address of a string variable that stores the value of the variable.
+// The environment variable accepts a path that is checked, when the variable is set, according to mode.
+// The default value is not checked.
+func (e *EnvSet) Path(name string, value string, mode PathMode, description string) *string {
+	p := new(string)
+	e.Var(newPathValue(value, p, mode), name, description)
+	return p
+}
+
+// Path defines a string environment variable with specified name, default value, check mode, and description string.
+// The return value is the address of a string variable that stores the value of the variable.
+// The environment variable accepts a path that is checked, when the variable is set, according to mode.
+// The default value is not checked.
+func Path(name string, value string, mode PathMode, description string) *string {
+	return Environment.Path(name, value, mode, description)
+}
+
+// URLVar defines a url.URL environment variable with specified name, default value, and description string.
+// The argument p points to a url.URL variable in which to store the value of the variable.
+// The environment variable accepts a URL. The password of the URL, if any, is masked
+// as "****" wherever the value is displayed, while p holds the full URL. Use [NewURLValue]
+// to display the password.
+func (e *EnvSet) URLVar(p *url.URL, name string, value *url.URL, description string) {
+	e.Var(newURLValue(value, p, true), name, description)
+}
+
+// URLVar defines a url.URL environment variable with specified name, default value, and description string.
+// The argument p points to a url.URL variable in which to store the value of the variable.
+// The environment variable accepts a URL. The password of the URL, if any, is masked
+// as "****" wherever the value is displayed, while p holds the full URL. Use [NewURLValue]
+// to display the password.
+func URLVar(p *url.URL, name string, value *url.URL, description string) {
+	Environment.Var(newURLValue(value, p, true), name, description)
+}
+
+// URL defines a url.URL environment variable with specified name, default value, and description string.
+// The return value is the address of a url.URL variable that stores the value of the variable.
+// The environment variable accepts a URL. The password of the URL, if any, is masked
+// as "****" wherever the value is displayed, while p holds the full URL. Use [NewURLValue]
+// to display the password.
+func (e *EnvSet) URL(name string, value *url.URL, description string) *url.URL {
+	p := new(url.URL)
+	e.Var(newURLValue(value, p, true), name, description)
+	return p
+}
+
+// URL defines a url.URL environment variable with specified name, default value, and description string.
+// The return value is the address of a url.URL variable that stores the value of the variable.
+// The environment variable accepts a URL. The password of the URL, if any, is masked
+// as "****" wherever the value is displayed, while p holds the full URL. Use [NewURLValue]
+// to display the password.
+func URL(name string, value *url.URL, description string) *url.URL {
+	return Environment.URL(name, value, description)
+}
+
+// NewURLValue returns a [Value] that stores a URL into p, for use with [EnvSet.Var].
+// p is initialized to value, if not nil. If redact is set the password of the URL
+// is masked as "****" wherever the value is displayed, as it is for [EnvSet.URLVar];
+// otherwise the URL is displayed in full. The String method always returns the full URL.
+func NewURLValue(p *url.URL, value *url.URL, redact bool) Value {
+	return newURLValue(value, p, redact)
+}
+
+// CronVar defines a string environment variable with specified name, default value, and description string.
+// The argument p points to a string variable in which to store the value of the variable.
+// The environment variable accepts a cron expression with five fields, minute, hour, day of month,
+// month and day of week, such as "*/15 9-17 * * MON-FRI", or a macro such as "@daily".
+// The default value must be a valid expression too.
+func (e *EnvSet) CronVar(p *string, name string, value string, description string) {
+	v, err := newCronValue(value, p, false)
+	e.checkDefault(name, err)
+	e.Var(v, name, description)
+}
+
+// CronVar defines a string environment variable with specified name, default value, and description string.
+// The argument p points to a string variable in which to store the value of the variable.
+// The environment variable accepts a cron expression with five fields, minute, hour, day of month,
+// month and day of week, such as "*/15 9-17 * * MON-FRI", or a macro such as "@daily".
+// The default value must be a valid expression too.
+func CronVar(p *string, name string, value string, description string) {
+	v, err := newCronValue(value, p, false)
+	Environment.checkDefault(name, err)
+	Environment.Var(v, name, description)
+}
+
+// Cron defines a string environment variable with specified name, default value, and description string.
+// The return value is the address of a string variable that stores the value of the variable.
+// The environment variable accepts a cron expression with five fields, minute, hour, day of month,
+// month and day of week, such as "*/15 9-17 * * MON-FRI", or a macro such as "@daily".
+// The default value must be a valid expression too.
+func (e *EnvSet) Cron(name string, value string, description string) *string {
+	p := new(string)
+	v, err := newCronValue(value, p, false)
+	e.checkDefault(name, err)
+	e.Var(v, name, description)
+	return p
+}
+
+// Cron defines a string environment variable with specified name, default value, and description string.
+// The return value is the address of a string variable that stores the value of the variable.
+// The environment variable accepts a cron expression with five fields, minute, hour, day of month,
+// month and day of week, such as "*/15 9-17 * * MON-FRI", or a macro such as "@daily".
+// The default value must be a valid expression too.
+func Cron(name string, value string, description string) *string {
+	return Environment.Cron(name, value, description)
+}
+
+// CronSecondsVar defines a string environment variable with specified name, default value, and description string.
+// The argument p points to a string variable in which to store the value of the variable.
+// The environment variable accepts a cron expression with six fields, a leading field for the second
+// followed by minute, hour, day of month, month and day of week, such as "0 */15 9-17 * * MON-FRI",
+// or a macro such as "@daily". The default value must be a valid expression too.
+func (e *EnvSet) CronSecondsVar(p *string, name string, value string, description string) {
+	v, err := newCronValue(value, p, true)
+	e.checkDefault(name, err)
+	e.Var(v, name, description)
+}
+
+// CronSecondsVar defines a string environment variable with specified name, default value, and description string.
+// The argument p points to a string variable in which to store the value of the variable.
+// The environment variable accepts a cron expression with six fields, a leading field for the second
+// followed by minute, hour, day of month, month and day of week, such as "0 */15 9-17 * * MON-FRI",
+// or a macro such as "@daily". The default value must be a valid expression too.
+func CronSecondsVar(p *string, name string, value string, description string) {
+	v, err := newCronValue(value, p, true)
+	Environment.checkDefault(name, err)
+	Environment.Var(v, name, description)
+}
+
+// LogFormatVar defines a LogFormat environment variable with specified name, default value, and description string.
+// The argument p points to a LogFormat variable in which to store the value of the variable.
+// The environment variable accepts one of "json", "text" or "logfmt", in any case.
+func (e *EnvSet) LogFormatVar(p *LogFormat, name string, value LogFormat, description string) {
+	e.Var(newLogFormatValue(value, p), name, description)
+}
+
+// LogFormatVar defines a LogFormat environment variable with specified name, default value, and description string.
+// The argument p points to a LogFormat variable in which to store the value of the variable.
+// The environment variable accepts one of "json", "text" or "logfmt", in any case.
+func LogFormatVar(p *LogFormat, name string, value LogFormat, description string) {
+	Environment.Var(newLogFormatValue(value, p), name, description)
+}
+
+// LogFormat defines a LogFormat environment variable with specified name, default value, and description string.
+// The return value is the address of a LogFormat variable that stores the value of the variable.
+// The environment variable accepts one of "json", "text" or "logfmt", in any case.
+func (e *EnvSet) LogFormat(name string, value LogFormat, description string) *LogFormat {
+	p := new(LogFormat)
+	e.Var(newLogFormatValue(value, p), name, description)
+	return p
+}
+
+// OptionalStringVar defines a string environment variable with specified name, default value, and description string.
+// The argument p points to an Optional[string] variable in which to store the value of the variable
+// and whether it was set.
+func (e *EnvSet) OptionalStringVar(p *Optional[string], name string, value string, description string) {
+	e.Var(newOptionalValue(value, p, func(v string, p *string) Value { return newStringValue(v, p) }), name, description)
+}
+
+// OptionalStringVar defines a string environment variable with specified name, default value, and description string.
+// The argument p points to an Optional[string] variable in which to store the value of the variable
+// and whether it was set.
+func OptionalStringVar(p *Optional[string], name string, value string, description string) {
+	Environment.OptionalStringVar(p, name, value, description)
+}
+
+// OptionalString defines a string environment variable with specified name, default value, and description string.
+// The return value is the address of an Optional[string] variable that stores the value of the variable
+// and whether it was set.
+func (e *EnvSet) OptionalString(name string, value string, description string) *Optional[string] {
+	p := new(Optional[string])
+	e.OptionalStringVar(p, name, value, description)
+	return p
+}
+
+// OptionalString defines a string environment variable with specified name, default value, and description string.
+// The return value is the address of an Optional[string] variable that stores the value of the variable
+// and whether it was set.
+func OptionalString(name string, value string, description string) *Optional[string] {
+	return Environment.OptionalString(name, value, description)
+}
+
+// OptionalIntVar defines an int environment variable with specified name, default value, and description string.
+// The argument p points to an Optional[int] variable in which to store the value of the variable
+// and whether it was set.
+func (e *EnvSet) OptionalIntVar(p *Optional[int], name string, value int, description string) {
+	e.Var(newOptionalValue(value, p, func(v int, p *int) Value { return newIntValue(v, p) }), name, description)
+}
+
+// OptionalIntVar defines an int environment variable with specified name, default value, and description string.
+// The argument p points to an Optional[int] variable in which to store the value of the variable
+// and whether it was set.
+func OptionalIntVar(p *Optional[int], name string, value int, description string) {
+	Environment.OptionalIntVar(p, name, value, description)
+}
+
+// OptionalInt defines an int environment variable with specified name, default value, and description string.
+// The return value is the address of an Optional[int] variable that stores the value of the variable
+// and whether it was set.
+func (e *EnvSet) OptionalInt(name string, value int, description string) *Optional[int] {
+	p := new(Optional[int])
+	e.OptionalIntVar(p, name, value, description)
+	return p
+}
+
+// OptionalInt defines an int environment variable with specified name, default value, and description string.
+// The return value is the address of an Optional[int] variable that stores the value of the variable
+// and whether it was set.
+func OptionalInt(name string, value int, description string) *Optional[int] {
+	return Environment.OptionalInt(name, value, description)
+}
+
+// OptionalBoolVar defines a bool environment variable with specified name, default value, and description string.
+// The argument p points to an Optional[bool] variable in which to store the value of the variable
+// and whether it was set.
+func (e *EnvSet) OptionalBoolVar(p *Optional[bool], name string, value bool, description string) {
+	e.Var(newOptionalValue(value, p, func(v bool, p *bool) Value { return newBoolValue(v, p) }), name, description)
+}
+
+// OptionalBoolVar defines a bool environment variable with specified name, default value, and description string.
+// The argument p points to an Optional[bool] variable in which to store the value of the variable
+// and whether it was set.
+func OptionalBoolVar(p *Optional[bool], name string, value bool, description string) {
+	Environment.OptionalBoolVar(p, name, value, description)
+}
+
+// OptionalBool defines a bool environment variable with specified name, default value, and description string.
+// The return value is the address of an Optional[bool] variable that stores the value of the variable
+// and whether it was set.
+func (e *EnvSet) OptionalBool(name string, value bool, description string) *Optional[bool] {
+	p := new(Optional[bool])
+	e.OptionalBoolVar(p, name, value, description)
+	return p
+}
+
+// OptionalBool defines a bool environment variable with specified name, default value, and description string.
+// The return value is the address of an Optional[bool] variable that stores the value of the variable
+// and whether it was set.
+func OptionalBool(name string, value bool, description string) *Optional[bool] {
+	return Environment.OptionalBool(name, value, description)
+}
+
+// OptionalDurationVar defines a time.Duration environment variable with specified name, default value, and description string.
+// The argument p points to an Optional[time.Duration] variable in which to store the value of the variable
+// and whether it was set.
+func (e *EnvSet) OptionalDurationVar(p *Optional[time.Duration], name string, value time.Duration, description string) {
+	e.Var(newOptionalValue(value, p, func(v time.Duration, p *time.Duration) Value { return newDurationValue(v, p) }), name, description)
+}
+
+// OptionalDurationVar defines a time.Duration environment variable with specified name, default value, and description string.
+// The argument p points to an Optional[time.Duration] variable in which to store the value of the variable
+// and whether it was set.
+func OptionalDurationVar(p *Optional[time.Duration], name string, value time.Duration, description string) {
+	Environment.OptionalDurationVar(p, name, value, description)
+}
+
+// OptionalDuration defines a time.Duration environment variable with specified name, default value, and description string.
+// The return value is the address of an Optional[time.Duration] variable that stores the value of the variable
+// and whether it was set.
+func (e *EnvSet) OptionalDuration(name string, value time.Duration, description string) *Optional[time.Duration] {
+	p := new(Optional[time.Duration])
+	e.OptionalDurationVar(p, name, value, description)
+	return p
+}
+
+// OptionalDuration defines a time.Duration environment variable with specified name, default value, and description string.
+// The return value is the address of an Optional[time.Duration] variable that stores the value of the variable
+// and whether it was set.
+func OptionalDuration(name string, value time.Duration, description string) *Optional[time.Duration] {
+	return Environment.OptionalDuration(name, value, description)
+}
+
+// BitmaskVar defines a uint64 environment variable with specified name, default value, and description string.
+// The argument p points to a uint64 variable in which to store the value of the variable.
+// The environment variable accepts a list of names from names or integers separated by |,
+// such as "READ|WRITE" or "3", and stores the OR of their bits. The default value must only have named bits.
+func (e *EnvSet) BitmaskVar(p *uint64, name string, value uint64, names map[string]uint64, description string) {
+	v, err := newBitmaskValue(value, p, names)
+	e.checkDefault(name, err)
+	e.Var(v, name, description)
+}
+
+// BitmaskVar defines a uint64 environment variable with specified name, default value, and description string.
+// The argument p points to a uint64 variable in which to store the value of the variable.
+// The environment variable accepts a list of names from names or integers separated by |,
+// such as "READ|WRITE" or "3", and stores the OR of their bits. The default value must only have named bits.
+func BitmaskVar(p *uint64, name string, value uint64, names map[string]uint64, description string) {
+	v, err := newBitmaskValue(value, p, names)
+	Environment.checkDefault(name, err)
+	Environment.Var(v, name, description)
+}
+
+// Bitmask defines a uint64 environment variable with specified name, default value, and description string.
+// The return value is the address of a uint64 variable that stores the value of the variable.
+// The environment variable accepts a list of names from names or integers separated by |,
+// such as "READ|WRITE" or "3", and stores the OR of their bits. The default value must only have named bits.
+func (e *EnvSet) Bitmask(name string, value uint64, names map[string]uint64, description string) *uint64 {
+	p := new(uint64)
+	v, err := newBitmaskValue(value, p, names)
+	e.checkDefault(name, err)
+	e.Var(v, name, description)
+	return p
+}
+
+// Bitmask defines a uint64 environment variable with specified name, default value, and description string.
+// The return value is the address of a uint64 variable that stores the value of the variable.
+// The environment variable accepts a list of names from names or integers separated by |,
+// such as "READ|WRITE" or "3", and stores the OR of their bits. The default value must only have named bits.
+func Bitmask(name string, value uint64, names map[string]uint64, description string) *uint64 {
+	return Environment.Bitmask(name, value, names, description)
+}
+
+// AtomicStringVar defines a string environment variable with specified name, default value, and description string.
+// The argument p points to an atomic.Pointer[string] in which to store the value of the variable.
+// The value is stored atomically, so p can be read by other goroutines while the variable is set again,
+// such as when the environment is reloaded. Only atomic variables are safe to set concurrently with reads.
+func (e *EnvSet) AtomicStringVar(p *atomic.Pointer[string], name string, value string, description string) {
+	e.Var(newAtomicStringValue(value, p), name, description)
+}
+
+// AtomicStringVar defines a string environment variable with specified name, default value, and description string.
+// The argument p points to an atomic.Pointer[string] in which to store the value of the variable.
+// The value is stored atomically, so p can be read by other goroutines while the variable is set again.
+func AtomicStringVar(p *atomic.Pointer[string], name string, value string, description string) {
+	Environment.Var(newAtomicStringValue(value, p), name, description)
+}
+
+// AtomicString defines a string environment variable with specified name, default value, and description string.
+// The return value is the address of an atomic.Pointer[string] that stores the value of the variable.
+// The value is stored atomically, so it can be read by other goroutines while the variable is set again,
+// such as when the environment is reloaded. Only atomic variables are safe to set concurrently with reads.
+func (e *EnvSet) AtomicString(name string, value string, description string) *atomic.Pointer[string] {
+	p := new(atomic.Pointer[string])
+	e.Var(newAtomicStringValue(value, p), name, description)
+	return p
+}
+
+// AtomicString defines a string environment variable with specified name, default value, and description string.
+// The return value is the address of an atomic.Pointer[string] that stores the value of the variable.
+// The value is stored atomically, so it can be read by other goroutines while the variable is set again.
+func AtomicString(name string, value string, description string) *atomic.Pointer[string] {
+	return Environment.AtomicString(name, value, description)
+}
+
+// AtomicInt64Var defines an int64 environment variable with specified name, default value, and description string.
+// The argument p points to an atomic.Int64 in which to store the value of the variable.
+// The value is stored atomically, so p can be read by other goroutines while the variable is set again,
+// such as when the environment is reloaded. Only atomic variables are safe to set concurrently with reads.
+func (e *EnvSet) AtomicInt64Var(p *atomic.Int64, name string, value int64, description string) {
+	e.Var(newAtomicInt64Value(value, p), name, description)
+}
+
+// AtomicInt64Var defines an int64 environment variable with specified name, default value, and description string.
+// The argument p points to an atomic.Int64 in which to store the value of the variable.
+// The value is stored atomically, so p can be read by other goroutines while the variable is set again.
+func AtomicInt64Var(p *atomic.Int64, name string, value int64, description string) {
+	Environment.Var(newAtomicInt64Value(value, p), name, description)
+}
+
+// AtomicInt64 defines an int64 environment variable with specified name, default value, and description string.
+// The return value is the address of an atomic.Int64 that stores the value of the variable.
+// The value is stored atomically, so it can be read by other goroutines while the variable is set again,
+// such as when the environment is reloaded. Only atomic variables are safe to set concurrently with reads.
+func (e *EnvSet) AtomicInt64(name string, value int64, description string) *atomic.Int64 {
+	p := new(atomic.Int64)
+	e.Var(newAtomicInt64Value(value, p), name, description)
+	return p
+}
+
+// AtomicInt64 defines an int64 environment variable with specified name, default value, and description string.
+// The return value is the address of an atomic.Int64 that stores the value of the variable.
+// The value is stored atomically, so it can be read by other goroutines while the variable is set again.
+func AtomicInt64(name string, value int64, description string) *atomic.Int64 {
+	return Environment.AtomicInt64(name, value, description)
+}
+
+// TextVar defines a environment variable with a specified name, default value, and description string.
+// The argument p must be a pointer to a variable that will hold the value
+// of the variable, and p must implement encoding.TextUnmarshaler.
+// If the environment variable is used, the environment variable's value will be passed to p's UnmarshalText method.
+// The type of the default value must be the same as the type of p.
+func (e *EnvSet) TextVar(p encoding.TextUnmarshaler, name string, value encoding.TextUnmarshaler, description string) {
+	e.Var(newTextValue(value, p), name, description)
+}
+
+// TextVar defines an environment variable with a specified name, default value, and description string.
+// The argument p must be a pointer to a variable that will hold the value
+// of the variable, and p must implement encoding.TextUnmarshaler.
+// If the environment variable is used, the environment variable's value will be passed to p's UnmarshalText method.
+// The type of the default value must be the same as the type of p.
+func TextVar(p encoding.TextUnmarshaler, name string, value encoding.TextUnmarshaler, description string) {
+	Environment.Var(newTextValue(value, p), name, description)
+}
+
+// Func defines an environment variable with the specified name and description string.
+// Each time the variable name is seen, fn is called with the associated value.
+// If fn returns a non-nil error, it will be treated as a parsing error.
+func (e *EnvSet) Func(name, description string, fn func(string) error) {
+	e.Var(funcValue(fn), name, description)
+}
+
+// Func defines an environment variable with the specified name and description string.
+// Each time the variable name is seen, fn is called with the associated value.
+// If fn returns a non-nil error, it will be treated as a parsing error.
+func Func(name, description string, fn func(string) error) {
+	Environment.Func(name, description, fn)
+}
+
+// BoolFunc defines an environment variable with the specified name and description string without requiring values.
+// Each time the variable name is seen, fn is called with the associated value.
+// If fn returns a non-nil error, it will be treated as a parsing error.
+func (e *EnvSet) BoolFunc(name, description string, fn func(string) error) {
+	e.Var(boolFuncValue(fn), name, description)
+}
+
+// BoolFunc defines an environment variable with the specified name and description string without requiring values.
+// Each time the variable name is seen, fn is called with the associated value.
+// If fn returns a non-nil error, it will be treated as a parsing error.
+func BoolFunc(name, description string, fn func(string) error) {
+	Environment.BoolFunc(name, description, fn)
+}
+
+// TupleVar defines an environment variable with the specified name and description string
+// whose value packs several related fields separated by sep. Each field is passed, in order,
+// to the parser at the same position, which is expected to store it; the number of fields
+// must match the number of parsers. If a parser returns a non-nil error, it will be treated
+// as a parsing error. For instance, a log rotation setting such as "100:7" can be stored with
+//
+//	var rotation struct {
+//		Size int64
+//		Keep int
+//	}
+//	e.TupleVar("LOG_ROTATION", ":", []func(string) error{
+//		func(s string) (err error) { rotation.Size, err = strconv.ParseInt(s, 10, 64); return },
+//		func(s string) (err error) { rotation.Keep, err = strconv.Atoi(s); return },
+//	}, "log rotation `size:keep`")
+func (e *EnvSet) TupleVar(name, sep string, parsers []func(string) error, description string) {
+	e.Var(tupleValue{sep: sep, parsers: parsers}, name, description)
+}
+
+// TupleVar defines an environment variable with the specified name and description string
+// whose value packs several related fields separated by sep. Each field is passed, in order,
+// to the parser at the same position; the number of fields must match the number of parsers.
+func TupleVar(name, sep string, parsers []func(string) error, description string) {
+	Environment.TupleVar(name, sep, parsers, description)
+}
+
+// Var defines an environment variable with the specified name and description string. They type and
+// value of the variable are represented by the first argument, of type [Value], which typically holds
+// a user-defined implementation of [Value]. For instance, the caller could create an environment
+// variable that turns a comma-separated string into a slice of strings by giving the slice the
+// methods of [Value]; in particular, [Set] would decompose the comma-separated string into the slice.
+func (e *EnvSet) Var(value Value, name string, description string) {
+	if err := e.TryVar(value, name, description); err != nil {
+		panic(e.sprintf("%v", err))
+	}
+}
+
+// TryVar is like [EnvSet.Var] but returns an error rather than panicking if
+// the variable cannot be defined: when name contains =, when a variable with
+// the same name is defined already, in which case the error matches
+// [ErrRedefined], or when name was set before being defined. The set is left
+// unchanged on error, so that variables defined at run time, such as by
+// plugins, can skip the names that conflict.
+func (e *EnvSet) TryVar(value Value, name string, description string) error {
+	if strings.Contains(name, "=") {
+		return fmt.Errorf("variable %q contains =", name)
+	}
+
+	// Remember the default value as a string; it won't change.
+	v := &Spec{Name: name, Description: description, Value: value, DefValue: value.String()}
+	if e.defined(name) {
+		return e.redefined(name) // happens only if variables are declared with identical names
+	}
+	if pos := e.undef[name]; pos != "" {
+		return fmt.Errorf("variable %s set at %s before being defined", name, pos)
+	}
+	if e.strictDefaults {
+		if err := roundTrip(value, v.DefValue); err != nil {
+			return fmt.Errorf("variable %s: default value %q does not round-trip: %v", name, v.DefValue, err)
+		}
+	}
+	if e.formal == nil {
+		e.formal = make(map[string]*Spec)
+	}
+	e.formal[name] = v
+	e.fold(name)
+	return nil
+}
+
+// Var defines an environment variable with the specified name and description string. The type and
+// value of the variable are represented by the first argument, of type [Value], which
+// typically holds a user-defined implementation of [Value]. For instance, the
+// caller could create an environment variable that turns a comma-separated string into a slice
+// of strings by giving the slice the methods of [Value]; in particular, [Set] would
+// decompose the comma-separated string into the slice.
+func Var(value Value, name string, description string) {
+	Environment.Var(value, name, description)
+}
+
+// TryVar is like [Var] but returns an error rather than panicking if the
+// variable cannot be defined. See [EnvSet.TryVar].
+func TryVar(value Value, name string, description string) error {
+	return Environment.TryVar(value, name, description)
+}
+
+// VarPrefixed is like [EnvSet.Var] but defines the variable prefix+name, so that
+// single variables of a set can belong to another namespace, such as "AWS_" for
+// the variable "REGION". The variable is known to the set, and printed by
+// [EnvSet.PrintDefaults], by its full name, which must be unique in the set.
+func (e *EnvSet) VarPrefixed(prefix string, value Value, name string, description string) {
+	e.Var(value, prefix+name, description)
+}
+
+// VarPrefixed is like [Var] but defines the variable prefix+name.
+func VarPrefixed(prefix string, value Value, name string, description string) {
+	Environment.Var(value, prefix+name, description)
+}
+
+// roundTrip checks that value accepts def, the text of its default value,
+// and that it still formats it as def afterwards. The check is done on a
+// copy of value, which is left unchanged; values of the package that cannot
+// be copied, and those defined outside the package, are not checked.
+func roundTrip(value Value, def string) error {
+	// a wrapped value formats as the value it wraps, which is simpler
+	// to copy: the wrapper may point to the variable of the value too.
+	for {
+		w, ok := value.(interface{ unwrap() Value })
+		if !ok {
+			break
+		}
+		value = w.unwrap()
+	}
+	c, ok := value.(interface{ clone() Value })
+	if !ok {
+		return nil
+	}
+	value = c.clone()
+	if err := value.Set(def); err != nil {
+		return err
+	}
+	if s := value.String(); s != def {
+		return fmt.Errorf("formatted back as %q", s)
+	}
+	return nil
+}
+
+// cloned returns a pointer to a copy of *p, for the clone methods of the values.
+func cloned[T any](p *T) *T {
+	c := *p
+	return &c
+}
+
+// checkDefault panics, naming the variable, if err, returned when checking
+// the default value of the variable name, is not nil.
+func (e *EnvSet) checkDefault(name string, err error) {
+	if err != nil {
+		panic(e.sprintf("variable %s: %v", name, err))
+	}
+}
+
+// defined reports whether name is already in use, either as the name of a
+// variable or as one of its fallbacks.
+func (e *EnvSet) defined(name string) bool {
+	_, inFormal := e.formal[name]
+	_, inFallback := e.fallback[name]
+	if inFormal || inFallback {
+		return true
+	}
+	_, ok := e.declared(name)
+	return ok
+}
+
+// declared returns the accepted name of the set that matches name once both
+// are converted to the case set with SetNameCase, if any.
+func (e *EnvSet) declared(name string) (string, bool) {
+	if e.nameCase == NameAsIs {
+		return "", false
+	}
+	n, ok := e.folded[e.nameCase.convert(name)]
+	return n, ok
+}
+
+// fold records the name of a variable or fallback in the index of declared.
+func (e *EnvSet) fold(name string) {
+	if e.nameCase == NameAsIs {
+		return
+	}
+	if e.folded == nil {
+		e.folded = make(map[string]string)
+	}
+	e.folded[e.nameCase.convert(name)] = name
+}
+
+// redefined returns the error for a variable redefinition.
+func (e *EnvSet) redefined(name string) error {
+	if e.name == "" {
+		return fmt.Errorf("%w: %s", ErrRedefined, name)
+	}
+	return fmt.Errorf("%s %w: %s", e.name, ErrRedefined, name)
+}
+
+// VarFallback defines an environment variable that can be set through any of
+// the given names. The first name is the canonical one and the others are
+// fallbacks, in decreasing order of priority. If more than one of the names is
+// present in the environment only the one with the highest priority is applied,
+// the others are ignored. This is useful to keep accepting a legacy name while
+// moving to a new one, e.g.
+//
+//	var db url.URL
+//	env.VarFallback(env.NewURLValue(&db, nil, true), []string{"APP_DATABASE_URL", "DATABASE_URL"}, "database address")
+func (e *EnvSet) VarFallback(value Value, names []string, description string) {
+	if len(names) == 0 {
+		panic(e.sprintf("variable has no names"))
+	}
+	canonical := names[0]
+	// check the fallbacks first so that nothing is defined if one is invalid
+	for i, name := range names[1:] {
+		if strings.Contains(name, "=") {
+			panic(e.sprintf("variable %q contains =", name))
+		}
+		if e.defined(name) || slices.Contains(names[:i+1], name) {
+			panic(e.sprintf("%v", e.redefined(name)))
+		}
+	}
+	e.Var(value, canonical, description)
+	for _, name := range names[1:] {
+		if e.fallback == nil {
+			e.fallback = make(map[string]string)
+		}
+		e.fallback[name] = canonical
+		e.fold(name)
+	}
+	if e.names == nil {
+		e.names = make(map[string][]string)
+	}
+	e.names[canonical] = slices.Clone(names)
+}
+
+// VarFallback defines an environment variable that can be set through any of
+// the given names. The first name is the canonical one and the others are
+// fallbacks, in decreasing order of priority. If more than one of the names is
+// present in the environment only the one with the highest priority is applied.
+func VarFallback(value Value, names []string, description string) {
+	Environment.VarFallback(value, names, description)
+}
+
+// FoundAs returns the name the variable name was set under, which for a
+// variable defined by [EnvSet.VarFallback] is the one of its names with the
+// highest priority that was present in the environment. It returns the empty
+// string if the variable is not set.
+func (e *EnvSet) FoundAs(name string) string {
+	return e.foundAs[name]
+}
+
+// FoundAs returns the name the variable name of the default set was set
+// under, see [EnvSet.FoundAs].
+func FoundAs(name string) string {
+	return Environment.FoundAs(name)
+}
+
+// SetMeta attaches the metadata value under key to the variable name,
+// replacing any previous value for key. Metadata is not used by the
+// package; it is available to tooling through [Spec.Metadata].
+// It returns an error if no such variable is defined.
+func (e *EnvSet) SetMeta(name, key string, value any) error {
+	spec, ok := e.formal[name]
+	if !ok {
+		return fmt.Errorf("no such variable %s", name)
+	}
+	if spec.Metadata == nil {
+		spec.Metadata = make(map[string]any)
+	}
+	spec.Metadata[key] = value
+	return nil
+}
+
+// SetMeta attaches the metadata value under key to the variable name.
+func SetMeta(name, key string, value any) error {
+	return Environment.SetMeta(name, key, value)
+}
+
+// GetMeta returns the metadata stored under key for the variable name
+// and reports whether it was present.
+func (e *EnvSet) GetMeta(name, key string) (value any, ok bool) {
+	spec, ok := e.formal[name]
+	if !ok {
+		return nil, false
+	}
+	value, ok = spec.Metadata[key]
+	return value, ok
+}
+
+// GetMeta returns the metadata stored under key for the variable name
+// and reports whether it was present.
+func GetMeta(name, key string) (value any, ok bool) {
+	return Environment.GetMeta(name, key)
+}
+
+// OnSet registers fn to be called each time the variable name is set from the
+// environment, right after its value is set, with the value returned by its
+// [Value.Get] method. Callbacks for the same variable run in the order they
+// were registered. It returns an error if no such variable is defined.
+func (e *EnvSet) OnSet(name string, fn func(value any)) error {
+	if _, ok := e.formal[name]; !ok {
+		return fmt.Errorf("no such variable %s", name)
+	}
+	if e.onSet == nil {
+		e.onSet = make(map[string][]func(any))
+	}
+	e.onSet[name] = append(e.onSet[name], fn)
+	return nil
+}
+
+// OnSet registers fn to be called each time the variable name is set from the
+// environment, with the value returned by its [Value.Get] method.
+func OnSet(name string, fn func(value any)) error {
+	return Environment.OnSet(name, fn)
+}
+
+// AllowFileRef lets the variable name reference a file: when its value starts
+// with @, as in "@/run/secrets/token", [EnvSet.Parse] reads the file at the
+// path that follows and uses its contents, without the trailing newline, as
+// the value. A leading @@ stands for a literal @ and other values are used as is.
+// Errors reading the file are reported as errors setting the variable.
+// Calling AllowFileRef on a variable that is not defined panics.
+func (e *EnvSet) AllowFileRef(name string) {
+	if _, ok := e.formal[name]; !ok {
+		panic(e.sprintf("file reference variable %s is not defined", name))
+	}
+	if e.fileRef == nil {
+		e.fileRef = make(map[string]bool)
+	}
+	e.fileRef[name] = true
+}
+
+// AllowFileRef lets the variable name reference a file, see [EnvSet.AllowFileRef].
+func AllowFileRef(name string) {
+	Environment.AllowFileRef(name)
+}
+
+// SetInverted inverts the bool variable name: [EnvSet.Parse] stores the
+// negation of its value, so that FEATURE=true turns the feature off. This lets a
+// feature that is on by default be disabled without a separate DISABLE_ variable.
+// Calling SetInverted on a variable that is not defined or is not a bool panics.
+func (e *EnvSet) SetInverted(name string) {
+	spec, ok := e.formal[name]
+	if !ok {
+		panic(e.sprintf("inverted variable %s is not defined", name))
+	}
+	if _, ok := spec.Value.(*boolValue); !ok {
+		panic(e.sprintf("inverted variable %s is not a bool", name))
+	}
+	if e.inverted == nil {
+		e.inverted = make(map[string]bool)
+	}
+	e.inverted[name] = true
+}
+
+// SetInverted inverts the bool variable name, see [EnvSet.SetInverted].
+func SetInverted(name string) {
+	Environment.SetInverted(name)
+}
+
+// SetPostParse sets the function that derives the final value of the variable
+// name: each time the variable is set, fn receives the value, as returned by
+// [Value.Get], and returns the value to store or an error that fails
+// [EnvSet.Parse]. For example, to make a path absolute:
+//
+//	e.SetPostParse("DATA_DIR", func(v any) (any, error) {
+//		return filepath.Abs(v.(string))
+//	})
+//
+// The value returned is written back through reflection to the variable the
+// [Value] points to, so it must have the type of the variable: returning an
+// int for a string variable or a float64 for an int one fails Parse.
+// This works for the values defined by [EnvSet.Int], [EnvSet.String],
+// [EnvSet.Duration] and the like, and for any Value that is a pointer to the
+// variable; writing to another Value fails Parse.
+// Calling SetPostParse on a variable that is not defined panics.
+func (e *EnvSet) SetPostParse(name string, fn func(any) (any, error)) {
+	if _, ok := e.formal[name]; !ok {
+		panic(e.sprintf("post-parsed variable %s is not defined", name))
+	}
+	if e.postParse == nil {
+		e.postParse = make(map[string]func(any) (any, error))
+	}
+	e.postParse[name] = fn
+}
+
+// SetPostParse sets the function that derives the final value of the
+// variable name, see [EnvSet.SetPostParse].
+func SetPostParse(name string, fn func(any) (any, error)) {
+	Environment.SetPostParse(name, fn)
+}
+
+// postProcess runs fn on the value and writes the result back to the
+// variable value points to.
+func postProcess(value Value, fn func(any) (any, error)) error {
+	result, err := fn(value.Get())
+	if err != nil {
+		return err
+	}
+	p := reflect.ValueOf(value)
+	if p.Kind() != reflect.Pointer || p.IsNil() || !p.Elem().CanSet() {
+		return fmt.Errorf("cannot write back to %T", value)
+	}
+	// the Value is typically a defined type over the type of the variable,
+	// such as intValue over int, so results are converted but only between
+	// types of the same kind: an int is not turned into a string or a float
+	// truncated into an int.
+	v, t := reflect.ValueOf(result), p.Elem().Type()
+	if !v.IsValid() || v.Kind() != t.Kind() || !v.Type().ConvertibleTo(t) {
+		return fmt.Errorf("cannot write back %T to %T", result, value)
+	}
+	p.Elem().Set(v.Convert(t))
+	return nil
+}
+
+// MarkExperimental marks the variable name as experimental: [EnvSet.Parse]
+// applies its value only if the experimental gate, an environment variable
+// named ENABLE_EXPERIMENTAL unless changed by [EnvSet.SetExperimentalGate],
+// is set to a true value. Otherwise the variable keeps its default value and
+// a warning is printed if it was set. Lazy variables, such as those defined
+// by [EnvSet.LazyInt], are read from the process environment and so is the
+// gate for them. Experimental variables are tagged as such by [EnvSet.PrintDefaults].
+// Calling MarkExperimental on a variable that is not defined panics.
+func (e *EnvSet) MarkExperimental(name string) {
+	if _, ok := e.formal[name]; !ok {
+		panic(e.sprintf("experimental variable %s is not defined", name))
+	}
+	if e.experimental == nil {
+		e.experimental = make(map[string]bool)
+	}
+	e.experimental[name] = true
+}
+
+// MarkExperimental marks the variable name as experimental, see [EnvSet.MarkExperimental].
+func MarkExperimental(name string) {
+	Environment.MarkExperimental(name)
+}
+
+// SetExperimentalGate sets the name of the environment variable that enables
+// the experimental variables of the set.
+func (e *EnvSet) SetExperimentalGate(name string) {
+	e.gate = name
+}
+
+// gateValue reports whether value turns the experimental gate on.
+func gateValue(value string) bool {
+	on, _ := strconv.ParseBool(value)
+	return on
+}
+
+// setFromProcess sets the variable to value, read from the process
+// environment, as set does with the experimental gate of the process
+// environment rather than the one of the last Parse.
+func (e *EnvSet) setFromProcess(spec *Spec, value string) error {
+	gateOn := e.gateOn
+	defer func() { e.gateOn = gateOn }()
+	e.gateOn = false
+	if value, ok := os.LookupEnv(e.experimentalGate()); ok && !e.sealed {
+		e.gateOn = gateValue(value)
+	}
+	return e.set(spec, spec.Name, value)
+}
+
+// experimentalGate returns the name of the experimental gate.
+func (e *EnvSet) experimentalGate() string {
+	if e.gate == "" {
+		return "ENABLE_EXPERIMENTAL"
+	}
+	return e.gate
+}
+
+// Required makes the variable name required: [EnvSet.Parse] fails, as set by
+// the error handling property of the set, if it is not in the environment.
+// All the missing variables are reported together.
+// Calling Required on a variable that is not defined panics.
+func (e *EnvSet) Required(name string) {
+	if _, ok := e.formal[name]; !ok {
+		panic(e.sprintf("required variable %s is not defined", name))
+	}
+	if e.required == nil {
+		e.required = make(map[string]bool)
+	}
+	e.required[name] = true
+}
+
+// Required makes the variable name required, see [EnvSet.Required].
+func Required(name string) {
+	Environment.Required(name)
+}
+
+// RequireIf makes the variable name required when cond holds. The condition is
+// evaluated by [EnvSet.Parse] once the whole environment is parsed and can inspect
+// the other variables of the set, for example through [EnvSet.Lookup]:
+//
+//	e.RequireIf("TLS_CERT", func(e *env.EnvSet) bool { return *tlsEnabled })
+//
+// Calling RequireIf on a variable that is not defined panics.
+func (e *EnvSet) RequireIf(name string, cond func(*EnvSet) bool) {
+	if _, ok := e.formal[name]; !ok {
+		panic(e.sprintf("required variable %s is not defined", name))
+	}
+	if e.conditions == nil {
+		e.conditions = make(map[string][]func(*EnvSet) bool)
+	}
+	e.conditions[name] = append(e.conditions[name], cond)
+}
+
+// RequireIf makes the variable name required when cond holds.
+func RequireIf(name string, cond func(*EnvSet) bool) {
+	Environment.RequireIf(name, cond)
+}
+
+// RequireAtLeast requires that at least n of the named variables are set when
+// [EnvSet.Parse] is done, such as two of three seed nodes:
+//
+//	e.RequireAtLeast(2, "SEED_1", "SEED_2", "SEED_3")
+//
+// Calling RequireAtLeast with a variable that is not defined panics.
+func (e *EnvSet) RequireAtLeast(n int, names ...string) {
+	for _, name := range names {
+		if _, ok := e.formal[name]; !ok {
+			panic(e.sprintf("required variable %s is not defined", name))
+		}
+	}
+	e.quorums = append(e.quorums, quorum{n, slices.Clone(names)})
+}
+
+// RequireAtLeast requires that at least n of the named variables are set.
+func RequireAtLeast(n int, names ...string) {
+	Environment.RequireAtLeast(n, names...)
+}
+
+// RestoreDefault sets the variable name back to its default value and marks
+// it as not set. It returns an error if no such variable is defined or if the
+// default value cannot be parsed back by the variable's [Value].
+// Variables defined with [EnvSet.Func], [EnvSet.BoolFunc] and [EnvSet.TupleVar]
+// have no default value to restore and always return an error.
+func (e *EnvSet) RestoreDefault(name string) error {
+	spec, ok := e.formal[name]
+	if !ok {
+		return fmt.Errorf("no such variable %s", name)
+	}
+	switch spec.Value.(type) {
+	case funcValue, boolFuncValue, tupleValue:
+		return fmt.Errorf("variable %s has no default value to restore", name)
+	}
+	if err := spec.Value.Set(spec.DefValue); err != nil {
+		return fmt.Errorf("cannot restore default value %q for variable %s: %v", spec.DefValue, name, err)
+	}
+	delete(e.actual, name)
+	delete(e.applied, name)
+	delete(e.foundAs, name)
+	return nil
+}
+
+// RestoreDefault sets the variable name back to its default value and marks
+// it as not set.
+func RestoreDefault(name string) error {
+	return Environment.RestoreDefault(name)
+}
+
+// Set sets the value of the variable name, as if it was found in the
+// environment, and marks it as set: the options of the set that transform
+// values, such as [EnvSet.SetTrimValues], [EnvSet.SetInverted] and
+// [EnvSet.SetPostParse], apply to value. It returns an error if no such variable
+// is defined or if the value cannot be parsed, in which case it is a
+// [*ParseError]. Unlike [EnvSet.Parse], Set does not print the usage message.
+func (e *EnvSet) Set(name, value string) error {
+	spec, ok := e.formal[cmp.Or(e.fallback[name], name)]
+	if !ok {
+		return fmt.Errorf("no such variable %s", name)
+	}
+	return e.setValue(spec, name, value)
+}
+
+// Set sets the value of the variable name in the default set.
+func Set(name, value string) error {
+	return Environment.Set(name, value)
+}
+
+// IsDefault reports whether the current value of the variable name, as
+// printed by its String method, equals its default value. Unlike presence in
+// the environment, a variable set explicitly to its default value is reported
+// as default too. It returns an error if no such variable is defined or if
+// the String method panics.
+func (e *EnvSet) IsDefault(name string) (ok bool, err error) {
+	spec, found := e.formal[name]
+	if !found {
+		return false, fmt.Errorf("no such variable %s", name)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic calling String method for variable %s: %v", name, r)
+		}
+	}()
+	return spec.Value.String() == spec.DefValue, nil
+}
+
+// IsDefault reports whether the current value of the variable name equals
+// its default value.
+func IsDefault(name string) (bool, error) {
+	return Environment.IsDefault(name)
+}
+
+// SetRequireAll sets whether every variable defined in the set must be present
+// in the environment. When enabled, [EnvSet.Parse] reports all the variables that
+// were not set, except those exempted with [EnvSet.RequireAllExcept].
+func (e *EnvSet) SetRequireAll(requireAll bool) {
+	e.requireAll = requireAll
+}
+
+// RequireAllExcept exempts the named variables from the check enabled by
+// [EnvSet.SetRequireAll], so that they can rely on their default value.
+func (e *EnvSet) RequireAllExcept(names ...string) {
+	if e.exempt == nil {
+		e.exempt = make(map[string]bool)
+	}
+	for _, name := range names {
+		e.exempt[name] = true
+	}
+}
+
+// load returns a function that, the first time it is called, sets the
+// variable name from the process environment. The variable is skipped by Parse.
+// An invalid value is reported as Parse does and the variable keeps its default.
+func (e *EnvSet) load(name string) func() {
+	if e.lazy == nil {
+		e.lazy = make(map[string]bool)
+	}
+	e.lazy[name] = true
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			value, ok := e.lookupEnv(name)
+			if !ok {
+				return
+			}
+			spec := e.formal[name]
+			e.lazyMu.Lock()
+			err := e.setFromProcess(spec, value)
+			if err != nil {
+				// Set may have stored part of the value before failing
+				spec.Value.Set(spec.DefValue)
+			}
+			e.lazyMu.Unlock()
+			if err != nil {
+				e.handleError(err)
+			}
+		})
+	}
+}
+
+// LazyBool defines a bool environment variable with specified name, default value, and description string.
+// The return value is a function that returns the value of the variable. The variable is not
+// set by [EnvSet.Parse]; it is read from the process environment the first time the function is called,
+// so an invalid value is not reported until then; the function then returns the default value.
+// Until then [EnvSet.Visit] does not visit the variable.
+func (e *EnvSet) LazyBool(name string, value bool, description string) func() bool {
+	p := new(bool)
+	e.Var(newBoolValue(value, p), name, description)
+	load := e.load(name)
+	return func() bool { load(); return *p }
+}
+
+// LazyBool defines a bool environment variable with specified name, default value, and description string.
+// The return value is a function that returns the value of the variable, read from the process environment
+// the first time the function is called.
+func LazyBool(name string, value bool, description string) func() bool {
+	return Environment.LazyBool(name, value, description)
+}
+
+// LazyInt defines an int environment variable with specified name, default value, and description string.
+// The return value is a function that returns the value of the variable. The variable is not
+// set by [EnvSet.Parse]; it is read from the process environment the first time the function is called,
+// so an invalid value is not reported until then; the function then returns the default value.
+// Until then [EnvSet.Visit] does not visit the variable.
+func (e *EnvSet) LazyInt(name string, value int, description string) func() int {
+	p := new(int)
+	e.Var(newIntValue(value, p), name, description)
+	load := e.load(name)
+	return func() int { load(); return *p }
+}
+
+// LazyInt defines an int environment variable with specified name, default value, and description string.
+// The return value is a function that returns the value of the variable, read from the process environment
+// the first time the function is called.
+func LazyInt(name string, value int, description string) func() int {
+	return Environment.LazyInt(name, value, description)
+}
+
+// LazyString defines a string environment variable with specified name, default value, and description string.
+// The return value is a function that returns the value of the variable. The variable is not
+// set by [EnvSet.Parse]; it is read from the process environment the first time the function is called,
+// so an invalid value is not reported until then; the function then returns the default value.
+// Until then [EnvSet.Visit] does not visit the variable.
+func (e *EnvSet) LazyString(name string, value string, description string) func() string {
+	p := new(string)
 	e.Var(newStringValue(value, p), name, description)
+	load := e.load(name)
+	return func() string { load(); return *p }
+}
+
+// LazyString defines a string environment variable with specified name, default value, and description string.
+// The return value is a function that returns the value of the variable, read from the process environment
+// the first time the function is called.
+func LazyString(name string, value string, description string) func() string {
+	return Environment.LazyString(name, value, description)
+}
+
+// LazyDuration defines a time.Duration environment variable with specified name, default value, and description string.
+// The return value is a function that returns the value of the variable. The variable is not
+// set by [EnvSet.Parse]; it is read from the process environment the first time the function is called,
+// so an invalid value is not reported until then; the function then returns the default value.
+// Until then [EnvSet.Visit] does not visit the variable.
+func (e *EnvSet) LazyDuration(name string, value time.Duration, description string) func() time.Duration {
+	p := new(time.Duration)
+	e.Var(newDurationValue(value, p), name, description)
+	load := e.load(name)
+	return func() time.Duration { load(); return *p }
+}
+
+// LazyDuration defines a time.Duration environment variable with specified name, default value, and description string.
+// The return value is a function that returns the value of the variable, read from the process environment
+// the first time the function is called.
+func LazyDuration(name string, value time.Duration, description string) func() time.Duration {
+	return Environment.LazyDuration(name, value, description)
+}
+
+// A Group defines variables of an [EnvSet] and returns, for each, a function
+// that reads its value. Reads go through [EnvSet.Get], so they are counted by
+// [EnvSet.AccessCounts]:
+//
+//	g := e.Group()
+//	port := g.Int("PORT", 8080, "port to listen on")
+//	...
+//	http.ListenAndServe(fmt.Sprintf(":%d", port()), nil)
+type Group struct {
+	e *EnvSet
+}
+
+// Group returns a [Group] defining variables of the set.
+func (e *EnvSet) Group() *Group {
+	return &Group{e}
+}
+
+// NewGroup returns a [Group] defining variables of the default set.
+func NewGroup() *Group {
+	return Environment.Group()
+}
+
+// accessor returns a function that reads the value of the variable name of e.
+func accessor[T any](e *EnvSet, name string) func() T {
+	return func() T { return e.Get(name).(T) }
+}
+
+// Var defines an environment variable with the specified name and description string,
+// as [EnvSet.Var] does, and returns a function that returns the value of the variable.
+func (g *Group) Var(value Value, name string, description string) func() any {
+	g.e.Var(value, name, description)
+	return accessor[any](g.e, name)
+}
+
+// Bool defines a bool environment variable with specified name, default value, and description string.
+// The return value is a function that returns the value of the variable.
+func (g *Group) Bool(name string, value bool, description string) func() bool {
+	g.e.Var(newBoolValue(value, new(bool)), name, description)
+	return accessor[bool](g.e, name)
+}
+
+// Int defines an int environment variable with specified name, default value, and description string.
+// The return value is a function that returns the value of the variable.
+func (g *Group) Int(name string, value int, description string) func() int {
+	g.e.Var(newIntValue(value, new(int)), name, description)
+	return accessor[int](g.e, name)
+}
+
+// Int64 defines an int64 environment variable with specified name, default value, and description string.
+// The return value is a function that returns the value of the variable.
+func (g *Group) Int64(name string, value int64, description string) func() int64 {
+	g.e.Var(newInt64Value(value, new(int64)), name, description)
+	return accessor[int64](g.e, name)
+}
+
+// Uint defines an uint environment variable with specified name, default value, and description string.
+// The return value is a function that returns the value of the variable.
+func (g *Group) Uint(name string, value uint, description string) func() uint {
+	g.e.Var(newUintValue(value, new(uint)), name, description)
+	return accessor[uint](g.e, name)
+}
+
+// Uint64 defines an uint64 environment variable with specified name, default value, and description string.
+// The return value is a function that returns the value of the variable.
+func (g *Group) Uint64(name string, value uint64, description string) func() uint64 {
+	g.e.Var(newUint64Value(value, new(uint64)), name, description)
+	return accessor[uint64](g.e, name)
+}
+
+// String defines a string environment variable with specified name, default value, and description string.
+// The return value is a function that returns the value of the variable.
+func (g *Group) String(name string, value string, description string) func() string {
+	g.e.Var(newStringValue(value, new(string)), name, description)
+	return accessor[string](g.e, name)
+}
+
+// Float64 defines a float64 environment variable with specified name, default value, and description string.
+// The return value is a function that returns the value of the variable.
+func (g *Group) Float64(name string, value float64, description string) func() float64 {
+	g.e.Var(newFloat64Value(value, new(float64)), name, description)
+	return accessor[float64](g.e, name)
+}
+
+// Duration defines a time.Duration environment variable with specified name, default value, and description string.
+// The return value is a function that returns the value of the variable.
+func (g *Group) Duration(name string, value time.Duration, description string) func() time.Duration {
+	g.e.Var(newDurationValue(value, new(time.Duration)), name, description)
+	return accessor[time.Duration](g.e, name)
+}
+
+// registry maps a type to the factory for the Value of its variables.
+// The types provided by the package are registered here.
+var (
+	registryMu sync.RWMutex
+	registry   = map[reflect.Type]func(ptr any, def any) Value{
+		reflect.TypeFor[bool]():    func(p, def any) Value { return newBoolValue(def.(bool), p.(*bool)) },
+		reflect.TypeFor[int]():     func(p, def any) Value { return newIntValue(def.(int), p.(*int)) },
+		reflect.TypeFor[int64]():   func(p, def any) Value { return newInt64Value(def.(int64), p.(*int64)) },
+		reflect.TypeFor[uint]():    func(p, def any) Value { return newUintValue(def.(uint), p.(*uint)) },
+		reflect.TypeFor[uint64]():  func(p, def any) Value { return newUint64Value(def.(uint64), p.(*uint64)) },
+		reflect.TypeFor[string]():  func(p, def any) Value { return newStringValue(def.(string), p.(*string)) },
+		reflect.TypeFor[float64](): func(p, def any) Value { return newFloat64Value(def.(float64), p.(*float64)) },
+		reflect.TypeFor[time.Duration](): func(p, def any) Value {
+			return newDurationValue(def.(time.Duration), p.(*time.Duration))
+		},
+	}
+)
+
+// RegisterType teaches the package how to build a [Value] for variables of type t,
+// which is used by [NewValue], [TypedVar] and [Must]. The factory receives a pointer
+// to a variable of type t, as a *t, and the default value, as a t; it must store the
+// default value into the variable and return a Value that sets it.
+// Registering a type again replaces its factory. The types bool, int, int64, uint,
+// uint64, string, float64 and time.Duration are registered by the package.
+func RegisterType(t reflect.Type, factory func(ptr any, def any) Value) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[t] = factory
+}
+
+// lookupType returns the factory registered for type t.
+func lookupType(t reflect.Type) (func(ptr any, def any) Value, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	factory, ok := registry[t]
+	return factory, ok
+}
+
+// NewValue returns a [Value] for the variable p, initialized to value, for use with
+// [EnvSet.Var]. It panics if no factory is registered for T with [RegisterType].
+func NewValue[T any](p *T, value T) Value {
+	factory, ok := lookupType(reflect.TypeFor[T]())
+	if !ok {
+		panic(fmt.Sprintf("no Value registered for type %v", reflect.TypeFor[T]()))
+	}
+	return factory(p, value)
+}
+
+// TypedVar defines an environment variable of type T with specified name, default value, and description string.
+// The argument p points to a T variable in which to store the value of the variable.
+// A factory for T must be registered with [RegisterType].
+func TypedVar[T any](p *T, name string, value T, description string) {
+	Environment.Var(NewValue(p, value), name, description)
+}
+
+// Must defines an environment variable in the default set with specified name, default value,
+// and description string, and returns its value. The type of the variable is the type of
+// the default value, which must be registered with [RegisterType].
+// Unlike the other definitions, the value is read from the process environment as
+// soon as the variable is defined rather than by [Parse]; Must panics if it cannot
+// be parsed. It is meant for small programs where a full [EnvSet] is not needed:
+//
+//	port := env.Must("PORT", 8080, "port to listen on")
+func Must[T any](name string, value T, description string) T {
+	p := new(T)
+	Environment.Var(NewValue(p, value), name, description)
+	if s, ok := Environment.lookupEnv(name); ok {
+		if err := Environment.setFromProcess(Environment.formal[name], s); err != nil {
+			panic(err)
+		}
+	}
+	return *p
+}
+
+// sprintf formats the message, prints it to output, and returns it.
+func (e *EnvSet) sprintf(format string, a ...any) string {
+	msg := fmt.Sprintf(format, a...)
+	fmt.Fprintln(e.Output(), msg)
+	return msg
+}
+
+// usage calls the Usage method for the env set if one is specified,
+// or the appropriate default usage function otherwise.
+func (e *EnvSet) usage() {
+	if e.Usage == nil {
+		e.defaultEnvironment()
+	} else {
+		e.Usage()
+	}
+}
+
+// help calls the usage function with the output set to the help output,
+// as the usage was requested.
+func (e *EnvSet) help() {
+	output := e.output
+	e.output = e.HelpOutput()
+	defer func() { e.output = output }()
+	e.usage()
+}
+
+// parseOne parses one variable. It reports wether a variable was seen.
+func (e *EnvSet) parseOne() (error, bool) {
+	if e.next >= len(e.environment) {
+		return nil, true
+	}
+	s := e.environment[e.next]
+	e.next++
+	// assume there are two strings now, name and value
+	key, value, _ := strings.Cut(s, "=")
+	if isHelp(key) {
+		e.help()
+		return ErrHelp, false
+	}
+	name, ok := e.match(key)
+	if !ok {
+		return nil, false
+	}
+	if isHelp(name) {
+		e.help()
+		return ErrHelp, false
+	}
+	canonical := name
+	if c, ok := e.fallback[name]; ok {
+		canonical = c
+	}
+	if names, ok := e.names[canonical]; ok {
+		// the variable has fallbacks, wait until the whole environment
+		// is seen to pick the name with the highest priority.
+		rank := slices.Index(names, name)
+		if c, seen := e.pending[canonical]; !seen || rank < c.rank {
+			if e.pending == nil {
+				e.pending = make(map[string]candidate)
+			}
+			e.pending[canonical] = candidate{rank: rank, name: name, value: value}
+		}
+		return nil, false
+	}
+	spec, ok := e.formal[name]
+	if !ok {
+		// saw an environment variable that is not in the list we want
+		// report the entry as found, with the prefix
+		if e.report != nil {
+			e.report.Unknown = append(e.report.Unknown, key)
+		}
+		if e.suggest {
+			e.suggestName(key)
+		}
+		return nil, false
+	}
+	if e.lazy[name] {
+		// parsed on first access
+		return nil, false
+	}
+	return e.set(spec, name, value), false
+}
+
+// match returns the name, of a variable or of one of its fallbacks, that the
+// name of an environment entry stands for. The name is returned as is if
+// it matches no variable, and ok is false if Parse ignores the entry.
+func (e *EnvSet) match(name string) (_ string, ok bool) {
+	if len(e.allowlist) > 0 && !e.allowlist[name] {
+		return "", false
+	}
+	name, ok = strings.CutPrefix(name, e.prefix)
+	if !ok {
+		return "", false
+	}
+	if declared, ok := e.declared(name); ok {
+		name = declared
+	}
+	return name, true
+}
+
+// isHelp reports whether name is one of the variables requesting help.
+func isHelp(name string) bool {
+	return name == "HELP" || name == "H"
+}
+
+// set applies value to the variable and records it as set.
+// The name is the one the value was found under and is used for error messages.
+func (e *EnvSet) set(spec *Spec, name, value string) error {
+	if err := e.setValue(spec, name, value); err != nil {
+		return e.fail(err)
+	}
+	return nil
+}
+
+// setValue is like set but does not print the error and the usage message.
+func (e *EnvSet) setValue(spec *Spec, name, value string) error {
+	if e.experimental[spec.Name] && !e.gateOn {
+		fmt.Fprintf(e.Output(), "env: ignoring experimental variable %s; set %s to enable it\n", name, e.experimentalGate())
+		return nil
+	}
+	v, err := e.transform(spec, value)
+	if err == nil {
+		err = spec.Value.Set(v)
+	}
+	if fn := e.postParse[spec.Name]; err == nil && fn != nil {
+		err = postProcess(spec.Value, fn)
+	}
+	if e.recording != nil {
+		entry := RecordEntry{Name: name, Value: e.displayValue(spec, value)}
+		if err != nil {
+			entry.Error = err.Error()
+		}
+		e.recording.Entries = append(e.recording.Entries, entry)
+	}
+	if err != nil {
+		perr := e.parseError(spec, name, value, err)
+		if e.report != nil {
+			// keep going, the errors are reported together
+			e.report.Errors = append(e.report.Errors, *perr)
+			return nil
+		}
+		return perr
+	}
+	if e.actual == nil {
+		e.actual = make(map[string]*Spec)
+	}
+	e.actual[spec.Name] = spec
+	if e.foundAs == nil {
+		e.foundAs = make(map[string]string)
+	}
+	e.foundAs[spec.Name] = name
+	e.apply(spec.Name, v)
+	if e.report != nil {
+		e.report.Set = append(e.report.Set, spec.Name)
+	}
+	for _, fn := range e.onSet[spec.Name] {
+		fn(spec.Value.Get())
+	}
+	return nil
+}
+
+// transform returns the value to pass to the Set method of the variable
+// according to the options of the set.
+func (e *EnvSet) transform(spec *Spec, value string) (string, error) {
+	if e.trimValues {
+		value = strings.TrimSpace(value)
+	}
+	if e.maxValueLen > 0 && len(value) > e.maxValueLen {
+		return "", fmt.Errorf("value of %d bytes longer than the limit of %d", len(value), e.maxValueLen)
+	}
+	if e.rejectControl {
+		for _, r := range value {
+			if unicode.IsControl(r) && !(r == '\t' && e.allowTab) {
+				return "", fmt.Errorf("control character %q in value", r)
+			}
+		}
+	}
+	if e.fileRef[spec.Name] && strings.HasPrefix(value, "@") {
+		if strings.HasPrefix(value, "@@") {
+			value = value[1:]
+		} else {
+			b, err := os.ReadFile(value[1:])
+			if err != nil {
+				return "", err
+			}
+			value = strings.TrimSuffix(string(b), "\n")
+		}
+	}
+	if e.templateData != nil {
+		var err error
+		if value, err = e.execute(spec.Name, value); err != nil {
+			return "", err
+		}
+	}
+	if _, ok := spec.Value.(*boolValue); ok && e.extendedBool {
+		value = boolWord(value)
+	}
+	if e.inverted[spec.Name] {
+		if b, err := strconv.ParseBool(value); err == nil {
+			value = strconv.FormatBool(!b)
+		}
+	}
+	if _, ok := spec.Value.(*durationValue); ok && e.isoDuration {
+		if _, err := time.ParseDuration(value); err != nil {
+			if d, err := parseISODuration(value); err == nil {
+				value = d.String()
+			}
+		}
+	}
+	return value, nil
+}
+
+// execute runs value as a template with the template data of the set.
+func (e *EnvSet) execute(name, value string) (string, error) {
+	t, err := template.New(name).Option("missingkey=error").Parse(value)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, e.templateData); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// applyPending applies the fallback candidates seen during Parse.
+func (e *EnvSet) applyPending() error {
+	for _, canonical := range slices.Sorted(maps.Keys(e.pending)) {
+		c := e.pending[canonical]
+		if err := e.set(e.formal[canonical], c.name, c.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Parse parses variables definitions from the environment list.
+// Must be called after all variables in the [EnvSet] are defined
+// and before the variables are accessed by the program.
+// The return value will be [ErrHelp] if HELP or H were set but not defined.
+func (e *EnvSet) Parse(environment []string) error {
+	defer func() { e.recording = nil }()
+	e.parsed = true
+	e.environment = environment
+	e.next = 0
+	e.pending = nil
+	e.gateOn = false
+	if len(e.experimental) > 0 {
+		gate := e.experimentalGate()
+		for _, s := range environment {
+			if name, value, _ := strings.Cut(s, "="); name == gate {
+				e.gateOn = gateValue(value)
+			}
+		}
+	}
+	for {
+		err, done := e.parseOne()
+		if done {
+			break
+		}
+		if err == nil {
+			continue
+		}
+		return e.handleError(err)
+	}
+	if err := e.applyPending(); err != nil {
+		return e.handleError(err)
+	}
+	if e.defaultExpansion {
+		if err := e.expandDefaults(); err != nil {
+			return e.handleError(e.fail(err))
+		}
+	}
+	var errs []error
+	if e.report != nil {
+		for i := range e.report.Errors {
+			errs = append(errs, &e.report.Errors[i])
+		}
+	}
+	errs = append(errs, e.check()...)
+	if len(errs) > 0 {
+		return e.handleError(e.fail(errors.Join(errs...)))
+	}
+	return nil
+}
+
+// ParseIter parses variables definitions pulled from next, until it reports
+// no more pairs, as [EnvSet.Parse] does with the entries "name=value".
+// It lets programs read the variables from any source:
+//
+//	rows, _ := db.Query("SELECT name, value FROM config")
+//	err := e.ParseIter(func() (name, value string, ok bool) {
+//		if !rows.Next() {
+//			return "", "", false
+//		}
+//		rows.Scan(&name, &value)
+//		return name, value, true
+//	})
+func (e *EnvSet) ParseIter(next func() (name, value string, ok bool)) error {
+	var environment []string
+	for {
+		name, value, ok := next()
+		if !ok {
+			break
+		}
+		environment = append(environment, name+"="+value)
+	}
+	return e.Parse(environment)
+}
+
+// ParseIter parses variables definitions pulled from next into the default set.
+func ParseIter(next func() (name, value string, ok bool)) error {
+	return Environment.ParseIter(next)
+}
+
+// ParseMap parses variables definitions from m, mapping names to values,
+// as [EnvSet.Parse] does. The entries are applied in lexicographical order
+// of their names; when the order matters, for example for variables defined
+// with [EnvSet.Func], use Parse with an explicit list instead.
+func (e *EnvSet) ParseMap(m map[string]string) error {
+	environment := make([]string, 0, len(m))
+	for _, name := range slices.Sorted(maps.Keys(m)) {
+		environment = append(environment, name+"="+m[name])
+	}
+	return e.Parse(environment)
 }
 
-// StringVar defines a string environment variable with specified name, default value, and description string.
-// The argument p points to a string variable in which to store the value of the variable.
-func StringVar(p *string, name string, value string, description string) {
-	Environment.Var(newStringValue(value, p), name, description)
+// ParseMap parses variables definitions from m into the default set.
+// See [EnvSet.ParseMap] for the order in which entries are applied.
+func ParseMap(m map[string]string) error {
+	return Environment.ParseMap(m)
 }
 
-// String defines a string environment variable with specified name, default value, and description string.
-// The return value is the address of a string variable that stores the value of the variable.
-func (e *EnvSet) String(name string, value string, description string) *string {
-	p := new(string)
-	e.Var(newStringValue(value, p), name, description)
-	return p
+// A ParseError records a value that could not be set on a variable.
+type ParseError struct {
+	Name  string // name of the variable
+	Value string // value as found in the environment, as shown by the display function
+	Err   error  // error returned by the variable's Set method, redacted if Value is
 }
 
-// String defines a string environment variable with specified name, default value, and description string.
-// The return value is the address of a string variable that stores the value of the variable.
-func String(name string, value string, description string) *string {
-	return Environment.String(name, value, description)
+func (p *ParseError) Error() string {
+	return fmt.Sprintf("invalid value %q for variable %s: %v", p.Value, p.Name, p.Err)
 }
 
-// Float64Var defines a float64 environment variable with specified name, default value, and description string.
-// The argument p points to a float64 variable in which to store the value of the variable.
-func (e *EnvSet) Float64Var(p *float64, name string, value float64, description string) {
-	e.Var(newFloat64Value(value, p), name, description)
+func (p *ParseError) Unwrap() error { return p.Err }
+
+// parseError returns the ParseError for value, found under name, that the
+// variable failed to set with err. If the value is displayed differently,
+// such as when it is masked, the message of err is hidden too as it may
+// quote the value.
+func (e *EnvSet) parseError(spec *Spec, name, value string, err error) *ParseError {
+	displayed := e.displayValue(spec, value)
+	if displayed != value {
+		err = &redactedError{err}
+	}
+	return &ParseError{Name: name, Value: displayed, Err: err}
 }
 
-// Float64Var defines a float64 environment variable with specified name, default value, and description string.
-// The argument p points to a float64 variable in which to store the value of the variable.
-func Float64Var(p *float64, name string, value float64, description string) {
-	Environment.Var(newFloat64Value(value, p), name, description)
+// A redactedError hides the message of the error it wraps, which may quote
+// a value that is not displayed as is. It still matches the wrapped error.
+type redactedError struct{ err error }
+
+func (r *redactedError) Error() string {
+	switch {
+	case errors.Is(r.err, errRange):
+		return errRange.Error()
+	case errors.Is(r.err, errParse):
+		return errParse.Error()
+	}
+	return "invalid value"
 }
 
-// Float64 defines a float64 environment variable with specified name, default value, and description string.
-// The return value is the address of a float64 variable that stores the value of the variable.
-func (e *EnvSet) Float64(name string, value float64, description string) *float64 {
-	p := new(float64)
-	e.Var(newFloat64Value(value, p), name, description)
-	return p
+func (r *redactedError) Unwrap() error { return r.err }
+
+// MarshalJSON encodes the error as an object with name, value and error fields.
+func (p ParseError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+		Error string `json:"error"`
+	}{p.Name, p.Value, p.Err.Error()})
 }
 
-// Float64 defines a float64 environment variable with specified name, default value, and description string.
-// The return value is the address of a float64 variable that stores the value of the variable.
-func Float64(name string, value float64, description string) *float64 {
-	return Environment.Float64(name, value, description)
+// A Report summarizes the outcome of [EnvSet.ParseReport].
+type Report struct {
+	Set     []string     `json:"set"`     // variables set from the environment
+	Unset   []string     `json:"unset"`   // variables left to their default value
+	Unknown []string     `json:"unknown"` // environment entries that match no variable
+	Errors  []ParseError `json:"errors"`  // values that could not be set
 }
 
-// DurationVar defines a time.Duration environment variable with specified name, default value, and description string.
-// The argument p points to a time.Duration variable in which to store the value of the variable.
-// The environment variable accepts a value acceptable to time.ParseDuration.
-func (e *EnvSet) DurationVar(p *time.Duration, name string, value time.Duration, description string) {
-	e.Var(newDurationValue(value, p), name, description)
+// ParseReport is like [EnvSet.Parse] but it does not stop at the first value
+// that cannot be set and returns a summary of what happened. The names in the
+// report are sorted. The returned error joins all the [ParseError] in the report
+// and is handled according to the error handling property of the set.
+func (e *EnvSet) ParseReport(environment []string) (*Report, error) {
+	r := &Report{}
+	e.report = r
+	defer func() { e.report = nil }()
+	err := e.Parse(environment)
+	for name := range e.formal {
+		if !slices.Contains(r.Set, name) {
+			r.Unset = append(r.Unset, name)
+		}
+	}
+	slices.Sort(r.Set)
+	slices.Sort(r.Unset)
+	slices.Sort(r.Unknown)
+	return r, err
 }
 
-// DurationVar defines a time.Duration environment variable with specified name, default value, and description string.
-// The argument p points to a time.Duration variable in which to store the value of the variable.
-// The environment variable accepts a value acceptable to time.ParseDuration.
-func DurationVar(p *time.Duration, name string, value time.Duration, description string) {
-	Environment.Var(newDurationValue(value, p), name, description)
+// A Recording holds the entries applied by a call to [EnvSet.Parse],
+// as captured by [EnvSet.Record].
+type Recording struct {
+	Entries []RecordEntry `json:"entries"`
 }
 
-// Duration defines a time.Duration environment variable with specified name, default value, and description string.
-// The return value is the address of a time.Duration variable that stores the value of the variable.
-// The environment variable accepts a value acceptable to time.ParseDuration.
-func (e *EnvSet) Duration(name string, value time.Duration, description string) *time.Duration {
-	p := new(time.Duration)
-	e.Var(newDurationValue(value, p), name, description)
-	return p
+// A RecordEntry is a value applied to a variable and its outcome.
+type RecordEntry struct {
+	Name  string `json:"name"`            // name as found in the environment
+	Value string `json:"value"`           // value, as shown by the display function
+	Error string `json:"error,omitempty"` // error setting the value, if any
 }
 
-// Duration defines a time.Duration environment variable with specified name, default value, and description string.
-// The return value is the address of a time.Duration variable that stores the value of the variable.
-// The environment variable accepts a value acceptable to time.ParseDuration.
-func Duration(name string, value time.Duration, description string) *time.Duration {
-	return Environment.Duration(name, value, description)
+// Record captures the entries applied to the variables of the set by the next
+// call to [EnvSet.Parse], and their outcome, in the returned [Recording].
+// Entries that match no variable are not recorded. Values go through the
+// function set with [EnvSet.SetDisplayFunc], so that secrets can be redacted
+// before the recording is shared, for example as JSON.
+func (e *EnvSet) Record() *Recording {
+	e.recording = &Recording{}
+	return e.recording
 }
 
-// TextVar defines a environment variable with a specified name, default value, and description string.
-// The argument p must be a pointer to a variable that will hold the value
-// of the variable, and p must implement encoding.TextUnmarshaler.
-// If the environment variable is used, the environment variable's value will be passed to p's UnmarshalText method.
-// The type of the default value must be the same as the type of p.
-func (e *EnvSet) TextVar(p encoding.TextUnmarshaler, name string, value encoding.TextUnmarshaler, description string) {
-	e.Var(newTextValue(value, p), name, description)
+// Record captures the entries applied by the next call to [Parse].
+func Record() *Recording {
+	return Environment.Record()
 }
 
-// TextVar defines an environment variable with a specified name, default value, and description string.
-// The argument p must be a pointer to a variable that will hold the value
-// of the variable, and p must implement encoding.TextUnmarshaler.
-// If the environment variable is used, the environment variable's value will be passed to p's UnmarshalText method.
-// The type of the default value must be the same as the type of p.
-func TextVar(p encoding.TextUnmarshaler, name string, value encoding.TextUnmarshaler, description string) {
-	Environment.Var(newTextValue(value, p), name, description)
+// Replay parses the entries of r as [EnvSet.Parse] does, reproducing the
+// recorded parse on a set defining the same variables. Redacted values are
+// applied as they are in r.
+func (e *EnvSet) Replay(r *Recording) error {
+	environment := make([]string, len(r.Entries))
+	for i, entry := range r.Entries {
+		environment[i] = entry.Name + "=" + entry.Value
+	}
+	return e.Parse(environment)
 }
 
-// Func defines an environment variable with the specified name and description string.
-// Each time the variable name is seen, fn is called with the associated value.
-// If fn returns a non-nil error, it will be treated as a parsing error.
-func (e *EnvSet) Func(name, description string, fn func(string) error) {
-	e.Var(funcValue(fn), name, description)
+// Replay parses the entries of r into the default set.
+func Replay(r *Recording) error {
+	return Environment.Replay(r)
 }
 
-// Func defines an environment variable with the specified name and description string.
-// Each time the variable name is seen, fn is called with the associated value.
-// If fn returns a non-nil error, it will be treated as a parsing error.
-func Func(name, description string, fn func(string) error) {
-	Environment.Func(name, description, fn)
+// expandDefaults sets the variables that were not set to their default value
+// with the references to other variables expanded.
+func (e *EnvSet) expandDefaults() error {
+	const resolving, resolved = 1, 2
+	state := make(map[string]int)
+	var resolve func(spec *Spec) error
+	resolve = func(spec *Spec) error {
+		if e.actual[spec.Name] != nil || state[spec.Name] == resolved || !strings.Contains(spec.DefValue, "$") {
+			return nil
+		}
+		if state[spec.Name] == resolving {
+			return fmt.Errorf("default value of variable %s is part of a cycle of references", spec.Name)
+		}
+		state[spec.Name] = resolving
+		var err error
+		value := os.Expand(spec.DefValue, func(name string) string {
+			ref, ok := e.formal[name]
+			if !ok {
+				err = cmp.Or(err, fmt.Errorf("default value of variable %s references undefined variable %s", spec.Name, name))
+				return ""
+			}
+			err = cmp.Or(err, resolve(ref))
+			return ref.Value.String()
+		})
+		if err != nil {
+			return err
+		}
+		state[spec.Name] = resolved
+		if err := spec.Value.Set(value); err != nil {
+			return e.parseError(spec, spec.Name, value, err)
+		}
+		e.apply(spec.Name, value)
+		return nil
+	}
+	for _, spec := range sortVariables(e.formal) {
+		if err := resolve(spec); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-// BoolFunc defines an environment variable with the specified name and description string without requiring values.
-// Each time the variable name is seen, fn is called with the associated value.
-// If fn returns a non-nil error, it will be treated as a parsing error.
-func (e *EnvSet) BoolFunc(name, description string, fn func(string) error) {
-	e.Var(boolFuncValue(fn), name, description)
+// A ConstraintError records the violation of a constraint on the variables
+// of a set, found by [EnvSet.Parse] once the whole environment is parsed.
+// Kind is the constraint violated: "required" for the variables required
+// with [EnvSet.Required], [EnvSet.SetRequireAll] or [EnvSet.RequireIf] and
+// "at-least" for [EnvSet.RequireAtLeast].
+type ConstraintError struct {
+	Kind  string   // constraint violated
+	Names []string // variables involved, as given to Lookup
+	Msg   string   // description of the violation, naming the variables as found in the environment
 }
 
-// BoolFunc defines an environment variable with the specified name and description string without requiring values.
-// Each time the variable name is seen, fn is called with the associated value.
-// If fn returns a non-nil error, it will be treated as a parsing error.
-func BoolFunc(name, description string, fn func(string) error) {
-	Environment.BoolFunc(name, description, fn)
+func (c *ConstraintError) Error() string {
+	return c.Msg
 }
 
-// Var defines an environment variable with the specified name and description string. They type and
-// value of the variable are represented by the first argument, of type [Value], which typically holds
-// a user-defined implementation of [Value]. For instance, the caller could create an environment
-// variable that turns a comma-separated string into a slice of strings by giving the slice the
-// methods of [Value]; in particular, [Set] would decompose the comma-separated string into the slice.
-func (e *EnvSet) Var(value Value, name string, description string) {
-	if strings.Contains(name, "=") {
-		panic(e.sprintf("variable %q contains =", name))
+// check verifies the constraints on the set once the whole environment is
+// parsed. All the violations are reported together.
+func (e *EnvSet) check() []error {
+	var errs []error
+	var missing []string
+	for _, spec := range sortVariables(e.formal) {
+		if e.isSet(spec.Name) {
+			continue
+		}
+		if e.isRequired(spec.Name) || e.requiredIf(spec.Name) {
+			missing = append(missing, spec.Name)
+		}
 	}
-
-	// Remember the default value as a string; it won't change.
-	v := &Spec{Name: name, Description: description, Value: value, DefValue: value.String()}
-	_, alreadyThere := e.formal[name]
-	if alreadyThere {
-		var msg string
-		if e.name == "" {
-			msg = e.sprintf("variable redefined: %s", name)
-		} else {
-			msg = e.sprintf("%s variable redefined: %s", e.name, name)
+	if len(missing) > 0 {
+		errs = append(errs, &ConstraintError{
+			Kind:  "required",
+			Names: missing,
+			Msg:   fmt.Sprintf("missing required variables: %s", strings.Join(e.prefixed(missing), ", ")),
+		})
+	}
+	for _, q := range e.quorums {
+		set := 0
+		for _, name := range q.names {
+			if e.isSet(name) {
+				set++
+			}
+		}
+		if set < q.n {
+			errs = append(errs, &ConstraintError{
+				Kind:  "at-least",
+				Names: q.names,
+				Msg:   fmt.Sprintf("%d of variables %s set, at least %d required", set, strings.Join(e.prefixed(q.names), ", "), q.n),
+			})
 		}
-		panic(msg) // happens only if variables are declared with identical names
 	}
-	if pos := e.undef[name]; pos != "" {
-		panic(fmt.Sprintf("variable %s set at %s before being defined", name, pos))
+	return errs
+}
+
+// prefixed returns the names with the prefix of the set.
+func (e *EnvSet) prefixed(names []string) []string {
+	if e.prefix == "" {
+		return names
 	}
-	if e.formal == nil {
-		e.formal = make(map[string]*Spec)
+	p := make([]string, len(names))
+	for i, name := range names {
+		p[i] = e.prefix + name
 	}
-	e.formal[name] = v
+	return p
 }
 
-// Var defines an environment variable with the specified name and description string. The type and
-// value of the variable are represented by the first argument, of type [Value], which
-// typically holds a user-defined implementation of [Value]. For instance, the
-// caller could create an environment variable that turns a comma-separated string into a slice
-// of strings by giving the slice the methods of [Value]; in particular, [Set] would
-// decompose the comma-separated string into the slice.
-func Var(value Value, name string, description string) {
-	Environment.Var(value, name, description)
+// isSet reports whether the variable name is set. A lazy variable not loaded
+// yet is set if it is present in the process environment.
+func (e *EnvSet) isSet(name string) bool {
+	if e.actual[name] != nil {
+		return true
+	}
+	if e.lazy[name] {
+		_, ok := e.lookupEnv(name)
+		return ok
+	}
+	return false
 }
 
-// sprintf formats the message, prints it to output, and returns it.
-func (e *EnvSet) sprintf(format string, a ...any) string {
-	msg := fmt.Sprintf(format, a...)
-	fmt.Fprintln(e.Output(), msg)
-	return msg
+// isRequired reports whether the variable name must be set.
+func (e *EnvSet) isRequired(name string) bool {
+	return e.required[name] || e.requireAll && !e.exempt[name]
+}
+
+// requiredIf reports whether any of the conditions registered with
+// RequireIf for the variable name holds.
+func (e *EnvSet) requiredIf(name string) bool {
+	for _, cond := range e.conditions[name] {
+		if cond(e) {
+			return true
+		}
+	}
+	return false
 }
 
-// failf prints to standard error a formatted error and usage message and
+// fail prints to standard error the error and usage message and
 // returns the error.
-func (e *EnvSet) failf(format string, a ...any) error {
-	msg := e.sprintf(format, a...)
+func (e *EnvSet) fail(err error) error {
+	fmt.Fprintln(e.Output(), err)
 	e.usage()
-	return errors.New(msg)
+	return err
 }
 
-// usage calls the Usage method for the env set if one is specified,
-// or the appropriate default usage function otherwise.
-func (e *EnvSet) usage() {
-	if e.Usage == nil {
-		e.defaultEnvironment()
-	} else {
-		e.Usage()
+// ParseOrHelp is like [EnvSet.Parse] but reports whether help was requested,
+// by setting HELP or H, instead of treating it as an error: if so it returns
+// true without parsing nor printing the usage message, leaving it to the caller.
+// Otherwise it parses the environment as Parse would with the [ContinueOnError]
+// error handling, regardless of the error handling property of the set.
+//
+//	help, err := e.ParseOrHelp(os.Environ())
+//	if help {
+//		e.PrintDefaults()
+//		os.Exit(0)
+//	}
+func (e *EnvSet) ParseOrHelp(environment []string) (helpRequested bool, err error) {
+	for _, s := range environment {
+		name, _, _ := strings.Cut(s, "=")
+		if isHelp(name) {
+			e.parsed = true
+			return true, nil
+		}
 	}
+	errorHandling := e.errorHandling
+	e.errorHandling = ContinueOnError
+	defer func() { e.errorHandling = errorHandling }()
+	return false, e.Parse(environment)
 }
 
-// parseOne parses one variable. It reports wether a variable was seen.
-func (e *EnvSet) parseOne() (error, bool) {
-	if len(e.environment) == 0 {
-		return nil, true
+// Reload parses the environment again, as [EnvSet.Parse] does, and returns the
+// names of the variables whose value changed, in lexicographical order.
+// Variables absent from environment keep their current value. The constraints
+// of the set are checked again and, if the new environment is invalid, the
+// previous values are restored and the error is returned, joined with any
+// error restoring them; Reload always behaves as if the error handling
+// property of the set were [ContinueOnError].
+//
+// Reload writes to the variables while other goroutines may be reading them;
+// only atomic variables, such as those defined by [EnvSet.AtomicString], are
+// safe to read concurrently with Reload.
+func (e *EnvSet) Reload(environment []string) (changed []string, err error) {
+	return e.reload(environment, false)
+}
+
+// reload implements Reload. If restore is set, the variables that were set
+// but are absent from environment are restored to their default value.
+func (e *EnvSet) reload(environment []string, restore bool) (changed []string, err error) {
+	actual := maps.Clone(e.actual)
+	applied := maps.Clone(e.applied)
+	foundAs := maps.Clone(e.foundAs)
+	if restore {
+		present := make(map[string]bool)
+		for _, s := range environment {
+			name, _, _ := strings.Cut(s, "=")
+			if name, ok := e.match(name); ok {
+				present[cmp.Or(e.fallback[name], name)] = true
+			}
+		}
+		for _, spec := range sortVariables(e.formal) {
+			if actual[spec.Name] == nil || present[spec.Name] {
+				continue
+			}
+			switch spec.Value.(type) {
+			case funcValue, boolFuncValue, tupleValue:
+				// variables with no default value keep their value
+				continue
+			}
+			if err := e.RestoreDefault(spec.Name); err != nil {
+				return nil, e.rollback(actual, applied, foundAs, err)
+			}
+		}
 	}
-	s := e.environment[0]
-	e.environment = e.environment[1:]
-	// assume there are two strings now, name and value
-	name, value, _ := strings.Cut(s, "=")
-	if name == "HELP" || name == "H" {
-		e.usage()
-		return ErrHelp, false
+	errorHandling := e.errorHandling
+	e.errorHandling = ContinueOnError
+	err = e.Parse(environment)
+	e.errorHandling = errorHandling
+	if err != nil {
+		return nil, e.rollback(actual, applied, foundAs, err)
+	}
+	for _, spec := range sortVariables(e.formal) {
+		text, ok := applied[spec.Name]
+		if newText, newOk := e.applied[spec.Name]; newOk != ok || newText != text {
+			changed = append(changed, spec.Name)
+			e.notify(spec.Name, spec.Value.String())
+		}
+	}
+	return changed, nil
+}
+
+// rollback restores the variables to the state recorded by actual, applied
+// and foundAs after reload failed with err, and returns err joined with any error
+// restoring them. A value may be changed even if setting it failed, so all
+// the variables are restored.
+func (e *EnvSet) rollback(actual map[string]*Spec, applied, foundAs map[string]string, err error) error {
+	errs := []error{err}
+	for _, spec := range sortVariables(e.formal) {
+		text, ok := applied[spec.Name]
+		if err := e.restore(spec, text, ok); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	e.actual = actual
+	e.applied = applied
+	e.foundAs = foundAs
+	return errors.Join(errs...)
+}
+
+// apply records text as the last text applied to the variable name on top
+// of its default value, from which reload can restore the variable.
+func (e *EnvSet) apply(name, text string) {
+	if e.applied == nil {
+		e.applied = make(map[string]string)
+	}
+	e.applied[name] = text
+}
+
+// restore sets the variable back to its default value and, if ok, applies
+// text on top of it, as recorded by apply. Variables with no default value
+// have no state to restore.
+func (e *EnvSet) restore(spec *Spec, text string, ok bool) error {
+	switch spec.Value.(type) {
+	case funcValue, boolFuncValue, tupleValue:
+		return nil
+	}
+	if err := spec.Value.Set(spec.DefValue); err != nil && !ok {
+		// a default value referencing other variables may only be
+		// valid once expanded, which text then is.
+		return fmt.Errorf("cannot restore default value %q for variable %s: %v", spec.DefValue, spec.Name, err)
 	}
-	spec, ok := e.formal[name]
 	if !ok {
-		// saw an environment variable that is not in the list we want
-		return nil, false
+		return nil
 	}
-	if err := spec.Value.Set(value); err != nil {
-		return e.failf("invalid value %q for variable %s: %v", value, name, err), false
+	err := spec.Value.Set(text)
+	if fn := e.postParse[spec.Name]; err == nil && fn != nil {
+		err = postProcess(spec.Value, fn)
 	}
-	if e.actual == nil {
-		e.actual = make(map[string]*Spec)
+	if err != nil {
+		return fmt.Errorf("cannot restore value for variable %s: %v", spec.Name, err)
 	}
-	e.actual[name] = spec
-	return nil, false
+	return nil
 }
 
-// Parse parses variables definitions from the environment list.
-// Must be called after all variables in the [EnvSet] are defined
-// and before the variables are accessed by the program.
-// The return value will be [ErrHelp] if HELP or H were set but not defined.
-func (e *EnvSet) Parse(environment []string) error {
-	e.parsed = true
-	e.environment = environment
-	for {
-		err, done := e.parseOne()
-		if done {
-			break
+// NotifyChange causes [EnvSet.Reload] to send the new value of the variable
+// name on ch whenever it changes. Sends do not block: if ch is not ready to
+// receive the value is dropped, so the caller should use a buffered channel.
+// A channel can be registered for more than one variable and a variable can
+// have more than one channel. Calling NotifyChange on a variable that is not
+// defined panics.
+func (e *EnvSet) NotifyChange(name string, ch chan<- string) {
+	if _, ok := e.formal[name]; !ok {
+		panic(e.sprintf("notified variable %s is not defined", name))
+	}
+	e.notifyMu.Lock()
+	defer e.notifyMu.Unlock()
+	if e.subscribers == nil {
+		e.subscribers = make(map[string][]chan<- string)
+	}
+	e.subscribers[name] = append(e.subscribers[name], ch)
+}
+
+// StopNotify causes the set to stop sending values on ch for any variable.
+func (e *EnvSet) StopNotify(ch chan<- string) {
+	e.notifyMu.Lock()
+	defer e.notifyMu.Unlock()
+	for name, chans := range e.subscribers {
+		e.subscribers[name] = slices.DeleteFunc(chans, func(c chan<- string) bool { return c == ch })
+	}
+}
+
+// notify sends value to the channels registered for the variable name.
+func (e *EnvSet) notify(name, value string) {
+	e.notifyMu.Lock()
+	defer e.notifyMu.Unlock()
+	for _, ch := range e.subscribers[name] {
+		select {
+		case ch <- value:
+		default:
 		}
-		if err == nil {
-			continue
+	}
+}
+
+// ParseAll parses the environment list into several sets, scanning it once
+// and passing each entry to every set that defines a matching variable, which
+// then parses its entries as [EnvSet.Parse] does. The errors of the sets are
+// handled according to their own error handling property and the ones that
+// are returned are joined together.
+//
+// If HELP or H is set, the usage message of every set is printed once and
+// [ErrHelp] is handled according to the error handling property of the
+// first set.
+func ParseAll(environment []string, sets ...*EnvSet) error {
+	entries := make([][]string, len(sets))
+	for _, s := range environment {
+		name, _, _ := strings.Cut(s, "=")
+		if isHelp(name) {
+			for _, e := range sets {
+				e.parsed = true
+				e.help()
+			}
+			if len(sets) == 0 {
+				return ErrHelp
+			}
+			return sets[0].handleError(ErrHelp)
 		}
-		switch e.errorHandling {
-		case ContinueOnError:
-			return err
-		case ExitOnError:
-			if err == ErrHelp {
-				os.Exit(0)
+		for i, e := range sets {
+			// the experimental gate is not a variable of the set
+			// but is read by Parse too.
+			gate := len(e.experimental) > 0 && name == e.experimentalGate()
+			if bare, ok := e.match(name); ok && e.defined(bare) || gate {
+				entries[i] = append(entries[i], s)
 			}
-			os.Exit(2)
-		case PanicOnError:
-			panic(err)
 		}
 	}
-	return nil
+	var errs []error
+	for i, e := range sets {
+		if err := e.Parse(entries[i]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// handleError acts on a parse error according to the error handling
+// property of the set.
+func (e *EnvSet) handleError(err error) error {
+	switch e.errorHandling {
+	case ExitOnError:
+		if err == ErrHelp {
+			os.Exit(0)
+		}
+		os.Exit(2)
+	case PanicOnError:
+		panic(err)
+	}
+	return err
+}
+
+// ParseEnviron parses the environment values from [os.Environ], as
+// [EnvSet.Parse] does. It returns [ErrSealed] if the set is sealed.
+func (e *EnvSet) ParseEnviron() error {
+	if e.sealed {
+		e.parsed = true
+		return e.handleError(e.fail(ErrSealed))
+	}
+	return e.Parse(os.Environ())
 }
 
 // Parse parses the environment values from [os.Environ]. Must be called
 // after all variables are defined and before variables are accessed by the program.
 func Parse() {
-	Environment.Parse(os.Environ())
+	Environment.ParseEnviron()
 }
 
 // Environment is the default set of environment values, parsed from [os.GetEnv].
@@ -956,10 +6001,21 @@ func (e *EnvSet) Init(name string, errorHandling ErrorHandling) {
 	e.errorHandling = errorHandling
 }
 
+// linked maps a FlagSet to its Usage functions before each call to Link,
+// the most recent last.
+var (
+	linkedMu sync.Mutex
+	linked   = make(map[*flag.FlagSet][]func())
+)
+
 // Link associates EnvSet e to FlagSet f.
 // Error messages when parsing command line flags will also print out
 // the description of the environment variables expected.
+// The link can be undone with [Unlink].
 func Link(f *flag.FlagSet, e *EnvSet) {
+	linkedMu.Lock()
+	linked[f] = append(linked[f], f.Usage)
+	linkedMu.Unlock()
 	flagSetUsage := f.Usage
 	if flagSetUsage == nil {
 		flagSetUsage = f.PrintDefaults
@@ -970,10 +6026,53 @@ func Link(f *flag.FlagSet, e *EnvSet) {
 	}
 }
 
+// WarnShadowed prints, to the output of es, a warning for every setting given
+// both as a flag set in fs and as a variable set in es, where the flag wins:
+//
+//	PORT set via both -port and env PORT; using -port
+//
+// The function name maps the name of a flag to the name of the variable it
+// shadows, or to "" if it shadows none. WarnShadowed must be called after
+// both fs and es are parsed and returns the names of the shadowed variables.
+func WarnShadowed(fs *flag.FlagSet, es *EnvSet, name func(flag string) string) []string {
+	var shadowed []string
+	fs.Visit(func(f *flag.Flag) {
+		v := name(f.Name)
+		if v == "" || es.actual[v] == nil {
+			return
+		}
+		fmt.Fprintf(es.Output(), "%s set via both -%s and env %s; using -%s\n", v, f.Name, v, f.Name)
+		shadowed = append(shadowed, v)
+	})
+	return shadowed
+}
+
+// Unlink undoes the most recent [Link] of f, restoring the Usage function f
+// had before that call, nil included. Calling Unlink on a FlagSet that is not
+// linked does nothing. Programs that do not want the usage of [flag.CommandLine] to describe
+// the environment variables of [Environment], as it does by default, can call
+//
+//	env.Unlink(flag.CommandLine)
+func Unlink(f *flag.FlagSet) {
+	linkedMu.Lock()
+	defer linkedMu.Unlock()
+	usages := linked[f]
+	if len(usages) == 0 {
+		return
+	}
+	f.Usage = usages[len(usages)-1]
+	if len(usages) == 1 {
+		delete(linked, f)
+	} else {
+		linked[f] = usages[:len(usages)-1]
+	}
+}
+
 func init() {
 	// Take over the default error reporting behavior of the flag package.
 	// By default the flag package will call the flag.CommandLine.Usage
 	// function when an error is encountered while parsing command line flags.
+	// Programs can opt out with Unlink(flag.CommandLine).
 
 	Link(flag.CommandLine, Environment)
 }
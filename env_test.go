@@ -0,0 +1,759 @@
+// Copyright 2024, Edoardo Putti
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/url"
+	"regexp"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSplitEscaped(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"a,b,c", []string{"a", "b", "c"}},
+		{`a\,b,c`, []string{"a,b", "c"}},
+		{`a\\,b`, []string{`a\`, "b"}},
+		{`a\\\,b`, []string{`a\,b`}},
+		{`a,b\`, []string{"a", `b\`}},
+		{`a\b`, []string{`a\b`}},
+		{"a,,b,", []string{"a", "", "b", ""}},
+	}
+	for _, tt := range tests {
+		if got := splitEscaped(tt.in, ","); !slices.Equal(got, tt.want) {
+			t.Errorf("splitEscaped(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestJoinEscapedRoundTrip(t *testing.T) {
+	elems := []string{"a,b", `c\`, `d\,e`, ""}
+	if got := splitEscaped(joinEscaped(elems, ","), ","); !slices.Equal(got, elems) {
+		t.Errorf("splitEscaped(joinEscaped(%q)) = %q", elems, got)
+	}
+}
+
+func TestSliceEmptySeparator(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("defining a slice with an empty separator did not panic")
+		}
+	}()
+	e := NewEnvSet("test", ContinueOnError)
+	var p []string
+	e.ValidatedStringsVar(&p, "L", nil, "", func(string) error { return nil }, "")
+}
+
+func TestURLPasswordMasked(t *testing.T) {
+	var b strings.Builder
+	e := NewEnvSet("test", ContinueOnError)
+	e.SetOutput(&b)
+	e.SetStrictDefaults(true)
+	def, _ := url.Parse("postgres://user:secret@db/x")
+	p := e.URL("DB", def, "database")
+	if pw, _ := p.User.Password(); pw != "secret" {
+		t.Errorf("password after definition = %q, want secret", pw)
+	}
+	if err := e.Parse([]string{"DB=postgres://user:other@db/x"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := e.Environ(), []string{"DB=postgres://user:other@db/x"}; !slices.Equal(got, want) {
+		t.Errorf("Environ() = %q, want %q", got, want)
+	}
+	if err := e.RestoreDefault("DB"); err != nil {
+		t.Fatal(err)
+	}
+	if pw, _ := p.User.Password(); pw != "secret" {
+		t.Errorf("password after RestoreDefault = %q, want secret", pw)
+	}
+	e.PrintDefaults()
+	if out := b.String(); strings.Contains(out, "secret") || !strings.Contains(out, "user:****@db") {
+		t.Errorf("PrintDefaults shows the password:\n%s", out)
+	}
+}
+
+func TestLazyInvalidKeepsDefault(t *testing.T) {
+	t.Setenv("LZ", "notanint")
+	var b strings.Builder
+	e := NewEnvSet("test", ContinueOnError)
+	e.SetOutput(&b)
+	lz := e.LazyInt("LZ", 7, "")
+	if got := lz(); got != 7 {
+		t.Errorf("LazyInt = %d, want the default 7", got)
+	}
+	if !strings.Contains(b.String(), "invalid value") {
+		t.Errorf("invalid value not reported, output:\n%s", b.String())
+	}
+}
+
+func TestLazyRequired(t *testing.T) {
+	t.Setenv("LR", "3")
+	e := NewEnvSet("test", ContinueOnError)
+	e.SetOutput(io.Discard)
+	lr := e.LazyInt("LR", 0, "")
+	e.Required("LR")
+	if err := e.Parse(nil); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if got := lr(); got != 3 {
+		t.Errorf("LazyInt = %d, want 3", got)
+	}
+}
+
+// lossyValue formats back a different value than the one it was set to.
+// It can be copied, as the values of the package, to be checked by
+// SetStrictDefaults.
+type lossyValue struct{ p *string }
+
+func (v *lossyValue) Set(s string) error { *v.p = s + "!"; return nil }
+func (v *lossyValue) Get() any           { return *v.p }
+func (v *lossyValue) clone() Value       { return &lossyValue{cloned(v.p)} }
+func (v *lossyValue) String() string {
+	if v.p == nil {
+		return ""
+	}
+	return *v.p
+}
+
+func TestStrictDefaultsLeaveValue(t *testing.T) {
+	e := NewEnvSet("test", ContinueOnError)
+	e.SetStrictDefaults(true)
+	opt := e.OptionalString("OPT", "x", "")
+	if opt.Set {
+		t.Error("OptionalString is set after definition")
+	}
+	n := e.Count("N", 2, "")
+	if *n != 2 {
+		t.Errorf("Count = %d after definition, want 2", *n)
+	}
+	s := "a"
+	v := &lossyValue{p: &s}
+	if err := e.TryVar(v, "L", ""); err == nil {
+		t.Error("TryVar accepted a default that does not round-trip")
+	}
+	if s != "a" {
+		t.Errorf("TryVar changed the value to %q", s)
+	}
+}
+
+func TestReloadRollback(t *testing.T) {
+	e := NewEnvSet("test", ContinueOnError)
+	e.SetOutput(io.Discard)
+	n := e.Int("N", 1, "")
+	def, _ := url.Parse("postgres://user:secret@db/x")
+	u := e.URL("DB", def, "")
+	if err := e.Parse([]string{"N=2"}); err != nil {
+		t.Fatal(err)
+	}
+	changed, err := e.Reload([]string{"N=2", "DB=postgres://user:other@db/x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"DB"}; !slices.Equal(changed, want) {
+		t.Errorf("changed = %q, want %q", changed, want)
+	}
+	if _, err := e.Reload([]string{"N=x", "DB=postgres://user:third@db/x"}); err == nil {
+		t.Fatal("Reload accepted an invalid value")
+	}
+	if *n != 2 {
+		t.Errorf("N = %d after failed Reload, want 2", *n)
+	}
+	if pw, _ := u.User.Password(); pw != "other" {
+		t.Errorf("password = %q after failed Reload, want other", pw)
+	}
+}
+
+func TestReloadFileRestoresAbsent(t *testing.T) {
+	e := NewEnvSet("test", ContinueOnError)
+	e.SetOutput(io.Discard)
+	e.SetPrefix("APP_")
+	a := e.Int("A", 1, "")
+	b := e.Int("B", 1, "")
+	if err := e.ParseFile(strings.NewReader("APP_A=2\nAPP_B=2\n")); err != nil {
+		t.Fatal(err)
+	}
+	changed, err := e.ReloadFile(strings.NewReader("APP_A=2\n# APP_B=2\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *a != 2 || *b != 1 {
+		t.Errorf("A, B = %d, %d after ReloadFile, want 2, 1", *a, *b)
+	}
+	if want := []string{"B"}; !slices.Equal(changed, want) {
+		t.Errorf("changed = %q, want %q", changed, want)
+	}
+}
+
+func TestPercent(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+		err  error
+	}{
+		{"50%", 0.5, nil},
+		{"0.5", 0.5, nil},
+		{"0%", 0, nil},
+		{"100%", 1, nil},
+		{"150%", 0, errRange},
+		{"-1%", 0, errRange},
+		{"NaN", 0, errRange},
+		{"NaN%", 0, errRange},
+		{"abc", 0, errParse},
+	}
+	for _, tt := range tests {
+		var p float64
+		err := newPercentValue(0, &p).Set(tt.in)
+		if !errors.Is(err, tt.err) || err == nil && p != tt.want {
+			t.Errorf("Set(%q) = %v, %v; want %v, %v", tt.in, p, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestPercentString(t *testing.T) {
+	for _, v := range []float64{0, 0.07, 0.5, 0.123, 1.0 / 3, 1} {
+		p := v
+		s := newPercentValue(v, &p).String()
+		var back float64
+		if err := newPercentValue(0, &back).Set(s); err != nil || back != v {
+			t.Errorf("%v formats as %q, which parses as %v, %v", v, s, back, err)
+		}
+	}
+	tests := []struct {
+		v    float64
+		want string
+	}{
+		{0, "0%"},
+		{0.5, "50%"},
+		{0.1, "10%"},
+		{1, "100%"},
+		{0.07, "7%"},
+		{0.123, "12.3%"},
+		{1.0 / 3, "33.33333333333333%"},
+	}
+	for _, tt := range tests {
+		p := tt.v
+		if s := newPercentValue(tt.v, &p).String(); s != tt.want {
+			t.Errorf("%v formats as %q, want %q", tt.v, s, tt.want)
+		}
+	}
+}
+
+func TestRollout(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+		err  error
+	}{
+		{"on", 1, nil},
+		{"off", 0, nil},
+		{"25%", 0.25, nil},
+		{"NaN", 0, errRange},
+		{"200%", 0, errRange},
+	}
+	for _, tt := range tests {
+		var p float64
+		err := newRolloutValue(0, &p).Set(tt.in)
+		if !errors.Is(err, tt.err) || err == nil && p != tt.want {
+			t.Errorf("Set(%q) = %v, %v; want %v, %v", tt.in, p, err, tt.want, tt.err)
+		}
+	}
+	for v, want := range map[float64]string{0: "off", 1: "on", 0.5: "50%", 0.07: "7%"} {
+		p := v
+		if s := newRolloutValue(v, &p).String(); s != want {
+			t.Errorf("%v formats as %q, want %q", v, s, want)
+		}
+	}
+}
+
+func TestSetTransforms(t *testing.T) {
+	e := NewEnvSet("test", ContinueOnError)
+	f := e.Bool("FEATURE", false, "")
+	e.SetInverted("FEATURE")
+	n := e.Int("N", 0, "")
+	e.SetPostParse("N", func(v any) (any, error) { return v.(int) * 2, nil })
+	e.SetTrimValues(true)
+	if err := e.Set("FEATURE", "true"); err != nil {
+		t.Fatal(err)
+	}
+	if *f {
+		t.Error("Set(FEATURE, true) stored true for an inverted variable")
+	}
+	if err := e.Set("N", " 5 "); err != nil {
+		t.Fatal(err)
+	}
+	if *n != 10 {
+		t.Errorf("N = %d, want 10", *n)
+	}
+}
+
+func TestAllowlistLazy(t *testing.T) {
+	t.Setenv("LA", "3")
+	e := NewEnvSet("test", ContinueOnError)
+	la := e.LazyInt("LA", 1, "")
+	e.SetAllowlist("OTHER")
+	if got := la(); got != 1 {
+		t.Errorf("LazyInt = %d, want the default 1 as LA is not allowed", got)
+	}
+}
+
+func TestPostParseType(t *testing.T) {
+	e := NewEnvSet("test", ContinueOnError)
+	e.SetOutput(io.Discard)
+	s := e.String("S", "", "")
+	e.SetPostParse("S", func(any) (any, error) { return 65, nil })
+	n := e.Int("N", 0, "")
+	e.SetPostParse("N", func(any) (any, error) { return 2.5, nil })
+	d := e.Duration("D", 0, "")
+	e.SetPostParse("D", func(v any) (any, error) { return v.(time.Duration) * 2, nil })
+	if err := e.Parse([]string{"S=x"}); err == nil {
+		t.Errorf("int written back to string variable as %q", *s)
+	}
+	if err := e.Parse([]string{"N=1"}); err == nil {
+		t.Errorf("float64 written back to int variable as %d", *n)
+	}
+	if err := e.Parse([]string{"D=1s"}); err != nil || *d != 2*time.Second {
+		t.Errorf("D = %v, %v; want 2s", *d, err)
+	}
+}
+
+func TestFallbackFoundAs(t *testing.T) {
+	e := NewEnvSet("test", ContinueOnError)
+	var timeout int
+	e.VarFallback(newIntValue(0, &timeout), []string{"APP_TIMEOUT", "TIMEOUT"}, "")
+	if got := e.FoundAs("APP_TIMEOUT"); got != "" {
+		t.Errorf("FoundAs before Parse = %q, want empty", got)
+	}
+	if err := e.Parse([]string{"TIMEOUT=5"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := e.FoundAs("APP_TIMEOUT"); got != "TIMEOUT" {
+		t.Errorf("FoundAs = %q, want TIMEOUT", got)
+	}
+}
+
+func TestFallbackInvalidDefinesNothing(t *testing.T) {
+	e := NewEnvSet("test", ContinueOnError)
+	e.SetOutput(io.Discard)
+	func() {
+		defer func() { recover() }()
+		var v int
+		e.VarFallback(newIntValue(0, &v), []string{"A", "B=C"}, "")
+	}()
+	if e.Lookup("A") != nil {
+		t.Error("A is defined although its fallback is invalid")
+	}
+}
+
+func TestSchemaAllowed(t *testing.T) {
+	e := NewEnvSet("test", ContinueOnError)
+	e.LogFormat("LOG", LogFormatText, "")
+	var features []string
+	e.EnumsVar(&features, "FEATURES", nil, []string{"auth", "cache"}, ",", "")
+	e.Int("N", 0, "")
+	var b strings.Builder
+	if err := e.Schema(&b); err != nil {
+		t.Fatal(err)
+	}
+	var vars []struct {
+		Name    string
+		Allowed []string
+	}
+	if err := json.Unmarshal([]byte(b.String()), &vars); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]string{
+		"FEATURES": {"auth", "cache"},
+		"LOG":      logFormats,
+		"N":        nil,
+	}
+	for _, v := range vars {
+		if !slices.Equal(v.Allowed, want[v.Name]) {
+			t.Errorf("%s allowed = %q, want %q", v.Name, v.Allowed, want[v.Name])
+		}
+	}
+}
+
+func TestParseAllExperimentalGate(t *testing.T) {
+	e := NewEnvSet("test", ContinueOnError)
+	e.SetOutput(io.Discard)
+	x := e.Int("X", 1, "")
+	e.MarkExperimental("X")
+	if err := ParseAll([]string{"ENABLE_EXPERIMENTAL=true", "X=2"}, e); err != nil {
+		t.Fatal(err)
+	}
+	if *x != 2 {
+		t.Errorf("X = %d, want 2 with the experimental gate on", *x)
+	}
+}
+
+func TestLazyExperimentalGate(t *testing.T) {
+	t.Setenv("LX", "2")
+	t.Setenv("ENABLE_EXPERIMENTAL", "true")
+	e := NewEnvSet("test", ContinueOnError)
+	e.SetOutput(io.Discard)
+	lx := e.LazyInt("LX", 1, "")
+	e.MarkExperimental("LX")
+	if got := lx(); got != 2 {
+		t.Errorf("LX = %d, want 2 with the experimental gate on", got)
+	}
+}
+
+func TestLazyInvalidReportedOnce(t *testing.T) {
+	t.Setenv("LZ", "notanint")
+	var b strings.Builder
+	e := NewEnvSet("test", ContinueOnError)
+	e.SetOutput(&b)
+	e.LazyInt("LZ", 7, "")()
+	if n := strings.Count(b.String(), "invalid value"); n != 1 {
+		t.Errorf("invalid value reported %d times, want once:\n%s", n, b.String())
+	}
+}
+
+func TestParseErrorRedacted(t *testing.T) {
+	e := NewEnvSet("test", ContinueOnError)
+	e.SetOutput(io.Discard)
+	var tokens []int
+	e.Var(NewSliceValue(&tokens, nil, ",", strconv.Atoi), "TOKENS", "")
+	e.Int("PORT", 0, "")
+	e.SetDisplayFunc(func(spec *Spec, value string) string {
+		if spec.Name == "TOKENS" {
+			return "****"
+		}
+		return value
+	})
+	err := e.Parse([]string{"TOKENS=1,s3cret"})
+	if err == nil || strings.Contains(err.Error(), "s3cret") {
+		t.Errorf("error shows the secret: %v", err)
+	}
+	var numErr *strconv.NumError
+	if !errors.As(err, &numErr) {
+		t.Errorf("error %v does not wrap the error of the element", err)
+	}
+	err = e.Parse([]string{"PORT=x"})
+	if err == nil || !strings.Contains(err.Error(), `"x"`) {
+		t.Errorf("error hides a value that is displayed: %v", err)
+	}
+}
+
+// definitionPanic returns the value define panics with, or nil.
+func definitionPanic(define func()) (msg any) {
+	defer func() { msg = recover() }()
+	define()
+	return nil
+}
+
+func TestInvalidDefault(t *testing.T) {
+	var fee int
+	var features []string
+	tests := []struct {
+		name   string
+		define func(e *EnvSet)
+	}{
+		{"TIMEOUT", func(e *EnvSet) { e.DurationRange("TIMEOUT", time.Minute, time.Second, 5*time.Second, "") }},
+		{"GRACE", func(e *EnvSet) { e.SignedDuration("GRACE", -time.Minute, RequirePositive, "") }},
+		{"FEE", func(e *EnvSet) { e.BasisPointsRangeVar(&fee, "FEE", 500, 0, 100, "") }},
+		{"REGION", func(e *EnvSet) { e.RegexpMatch("REGION", "x", regexp.MustCompile(`^[a-z]{2}-[0-9]$`), "") }},
+		{"BACKUP", func(e *EnvSet) { e.Cron("BACKUP", "not cron", "") }},
+		{"PERMS", func(e *EnvSet) { e.Bitmask("PERMS", 0x8, map[string]uint64{"read": 1, "write": 2}, "") }},
+		{"FEATURES", func(e *EnvSet) { e.EnumsVar(&features, "FEATURES", []string{"x"}, []string{"auth"}, ",", "") }},
+	}
+	for _, tt := range tests {
+		e := NewEnvSet("test", ContinueOnError)
+		e.SetOutput(io.Discard)
+		msg := definitionPanic(func() { tt.define(e) })
+		if s, _ := msg.(string); !strings.Contains(s, tt.name) {
+			t.Errorf("%s: panic %v does not name the variable", tt.name, msg)
+		}
+	}
+}
+
+func TestSignedDuration(t *testing.T) {
+	tests := []struct {
+		policy SignPolicy
+		in     string
+		ok     bool
+	}{
+		{AllowNegative, "-5m", true},
+		{AllowNegative, "0s", true},
+		{AllowNegative, "5m", true},
+		{RequirePositive, "-5m", false},
+		{RequirePositive, "0s", false},
+		{RequirePositive, "5m", true},
+		{RequireNonNegative, "-5m", false},
+		{RequireNonNegative, "0s", true},
+		{RequireNonNegative, "5m", true},
+	}
+	for _, tt := range tests {
+		var p time.Duration
+		v, err := newSignedDurationValue(time.Minute, &p, tt.policy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, _ := time.ParseDuration(tt.in)
+		err = v.Set(tt.in)
+		if tt.ok && (err != nil || p != want) {
+			t.Errorf("policy %d: Set(%q) = %v, %v; want %v", tt.policy, tt.in, p, err, want)
+		}
+		if !tt.ok && !errors.Is(err, errRange) {
+			t.Errorf("policy %d: Set(%q) = %v, want an error matching errRange", tt.policy, tt.in, err)
+		}
+	}
+}
+
+func TestCheckCollisions(t *testing.T) {
+	a := NewEnvSet("a", ContinueOnError)
+	a.SetPrefix("APP_")
+	a.String("DB_HOST", "", "")
+	a.String("PORT", "", "")
+	b := NewEnvSet("b", ContinueOnError)
+	b.SetPrefix("APP_DB_")
+	b.String("HOST", "", "")
+	if got, want := a.CheckCollisions(b), []string{"APP_DB_HOST"}; !slices.Equal(got, want) {
+		t.Errorf("CheckCollisions = %q, want %q", got, want)
+	}
+	c := NewEnvSet("c", ContinueOnError)
+	c.String("PORT", "", "")
+	c.String("port", "", "")
+	if got := c.CheckCollisions(a); got == nil || len(got) != 0 && runtime.GOOS != "windows" {
+		t.Errorf("CheckCollisions = %#v, want an empty slice", got)
+	}
+}
+
+func TestParsedWithoutParse(t *testing.T) {
+	e := NewEnvSet("test", ContinueOnError)
+	if help, _ := e.ParseOrHelp([]string{"HELP=1"}); !help || !e.Parsed() {
+		t.Errorf("ParseOrHelp = %v, Parsed() = %v; want true, true", help, e.Parsed())
+	}
+	s := NewEnvSet("sealed", ContinueOnError)
+	s.SetOutput(io.Discard)
+	s.SetSealed(true)
+	if err := s.ParseEnviron(); !errors.Is(err, ErrSealed) || !s.Parsed() {
+		t.Errorf("ParseEnviron = %v, Parsed() = %v; want ErrSealed, true", err, s.Parsed())
+	}
+}
+
+func TestPrefix(t *testing.T) {
+	var b strings.Builder
+	e := NewEnvSet("test", ContinueOnError)
+	e.SetOutput(io.Discard)
+	e.SetHelpOutput(&b)
+	e.SetPrefix("MYAPP_")
+	port := e.Int("PORT", 1, "")
+	host := e.String("HOST", "x", "")
+	if err := e.Parse([]string{"MYAPP_PORT=80", "HOST=ignored"}); err != nil {
+		t.Fatal(err)
+	}
+	if *port != 80 {
+		t.Errorf("PORT = %d, want 80 from MYAPP_PORT", *port)
+	}
+	if *host != "x" {
+		t.Errorf("HOST = %q, want the default as HOST has no prefix", *host)
+	}
+	if e.Lookup("PORT") == nil {
+		t.Error("Lookup does not take the bare name")
+	}
+	for _, help := range []string{"HELP", "MYAPP_HELP", "MYAPP_H"} {
+		b.Reset()
+		if err := e.Parse([]string{help + "=1"}); err != ErrHelp {
+			t.Errorf("Parse(%s) = %v, want ErrHelp", help, err)
+		}
+		if !strings.Contains(b.String(), "MYAPP_PORT") {
+			t.Errorf("help for %s does not show the prefixed names:\n%s", help, b.String())
+		}
+	}
+}
+
+func TestPrefixRequired(t *testing.T) {
+	e := NewEnvSet("test", ContinueOnError)
+	e.SetOutput(io.Discard)
+	e.SetPrefix("MYAPP_")
+	e.Int("PORT", 1, "")
+	e.Required("PORT")
+	err := e.Parse(nil)
+	if err == nil || !strings.Contains(err.Error(), "MYAPP_PORT") {
+		t.Errorf("Parse = %v, want the missing MYAPP_PORT reported", err)
+	}
+}
+
+func TestPrefixUnknown(t *testing.T) {
+	var b strings.Builder
+	e := NewEnvSet("test", ContinueOnError)
+	e.SetOutput(&b)
+	e.SetPrefix("APP_")
+	e.SetSuggestions(true)
+	e.Int("PORT", 1, "")
+	r, err := e.ParseReport([]string{"APP_PROT=3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(r.Unknown, []string{"APP_PROT"}) {
+		t.Errorf("Unknown = %q, want [APP_PROT]", r.Unknown)
+	}
+	if want := "unknown variable APP_PROT; did you mean APP_PORT?"; !strings.Contains(b.String(), want) {
+		t.Errorf("output %q, want %q", b.String(), want)
+	}
+}
+
+func TestNameUpper(t *testing.T) {
+	e := NewEnvSet("test", ContinueOnError)
+	e.SetOutput(io.Discard)
+	port := e.Int("HttpPort", 80, "")
+	e.SetNameCase(NameUpper)
+	if err := e.Parse([]string{"HTTPPORT=8080"}); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if *port != 8080 {
+		t.Errorf("HttpPort = %d, want 8080", *port)
+	}
+}
+
+func TestNameUpperLazy(t *testing.T) {
+	t.Setenv("LAZYPORT", "8080")
+	e := NewEnvSet("test", ContinueOnError)
+	e.SetOutput(io.Discard)
+	port := e.LazyInt("LazyPort", 80, "")
+	e.SetNameCase(NameUpper)
+	if err := e.Parse(nil); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if got := port(); got != 8080 {
+		t.Errorf("LazyPort = %d, want 8080", got)
+	}
+}
+
+func TestExtendedBool(t *testing.T) {
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{"yes", true},
+		{"OFF", false},
+		{"Enabled", true},
+		{"disabled", false},
+		{"1", true},
+	}
+	for _, tt := range tests {
+		e := NewEnvSet("test", ContinueOnError)
+		e.SetOutput(io.Discard)
+		e.SetExtendedBool(true)
+		b := e.Bool("B", !tt.want, "")
+		if err := e.Parse([]string{"B=" + tt.value}); err != nil {
+			t.Errorf("Parse(B=%s): %v", tt.value, err)
+			continue
+		}
+		if *b != tt.want {
+			t.Errorf("B=%s: got %t, want %t", tt.value, *b, tt.want)
+		}
+	}
+	e := NewEnvSet("test", ContinueOnError)
+	e.SetOutput(io.Discard)
+	e.SetExtendedBool(true)
+	e.Bool("B", false, "")
+	if err := e.Parse([]string{"B=maybe"}); !errors.Is(err, errParse) {
+		t.Errorf("Parse(B=maybe) = %v, want errParse", err)
+	}
+}
+
+func TestInverted(t *testing.T) {
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{"true", false},
+		{"false", true},
+		{"1", false},
+	}
+	for _, tt := range tests {
+		e := NewEnvSet("test", ContinueOnError)
+		e.SetOutput(io.Discard)
+		f := e.Bool("FEATURE", !tt.want, "")
+		e.SetInverted("FEATURE")
+		if err := e.Parse([]string{"FEATURE=" + tt.value}); err != nil {
+			t.Errorf("Parse(FEATURE=%s): %v", tt.value, err)
+			continue
+		}
+		if *f != tt.want {
+			t.Errorf("FEATURE=%s: got %t, want %t", tt.value, *f, tt.want)
+		}
+	}
+}
+
+func TestTrimValues(t *testing.T) {
+	e := NewEnvSet("test", ContinueOnError)
+	e.SetOutput(io.Discard)
+	e.SetTrimValues(true)
+	b := e.Bool("DEBUG", false, "")
+	port := e.Int("PORT", 0, "")
+	s := e.String("GREETING", "", "")
+	if err := e.Parse([]string{"DEBUG= true", "PORT=8080 ", "GREETING= hello world "}); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if !*b {
+		t.Error("DEBUG = false, want true")
+	}
+	if *port != 8080 {
+		t.Errorf("PORT = %d, want 8080", *port)
+	}
+	if *s != "hello world" {
+		t.Errorf("GREETING = %q, want %q", *s, "hello world")
+	}
+}
+
+func TestValidatedStringsFirst(t *testing.T) {
+	e := NewEnvSet("test", ContinueOnError)
+	e.SetOutput(io.Discard)
+	var hosts []string
+	e.ValidatedStringsVar(&hosts, "HOSTS", nil, ",", func(s string) error {
+		if s == "" {
+			return errors.New("empty host")
+		}
+		return nil
+	}, "")
+	err := e.Parse([]string{"HOSTS=a,,b,"})
+	if err == nil || !strings.Contains(err.Error(), "element 2") || strings.Contains(err.Error(), "element 4") {
+		t.Errorf("Parse = %v, want only element 2 reported", err)
+	}
+}
+
+func TestAddrPortsFirst(t *testing.T) {
+	e := NewEnvSet("test", ContinueOnError)
+	e.SetOutput(io.Discard)
+	e.AddrPorts("PEERS", nil, ",", "")
+	err := e.Parse([]string{"PEERS=10.0.0.1:7946,bad,worse"})
+	if err == nil || !strings.Contains(err.Error(), "element 2") || strings.Contains(err.Error(), "element 3") {
+		t.Errorf("Parse = %v, want only element 2 reported", err)
+	}
+}
+
+func TestTypeNameGeneric(t *testing.T) {
+	type route struct {
+		Host string `env:"host"`
+	}
+	var ints []int
+	var routes []route
+	tests := []struct {
+		value Value
+		want  string
+	}{
+		{NewJSONSliceValue(&ints, nil), "json"},
+		{NewSliceValue(&ints, nil, ",", strconv.Atoi), "list"},
+		{newStructSliceValue(nil, &routes, "|", ";", "="), "list"},
+	}
+	for _, tt := range tests {
+		if got := typeName(tt.value); got != tt.want {
+			t.Errorf("typeName(%T) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
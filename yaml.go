@@ -0,0 +1,122 @@
+// Copyright 2024, Edoardo Putti
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ParseYAML parses variables definitions from r, a YAML document made of
+// "key: value" pairs only, and applies them as [EnvSet.Parse] does, with each
+// key being the name of a variable. Values can be plain, single quoted or double
+// quoted scalars and # starts a comment. Nested mappings, lists and the other
+// features of YAML are not supported and are reported as errors.
+//
+//	PORT: 8080 # default dev port
+//	GREETING: "hello # world"
+func (e *EnvSet) ParseYAML(r io.Reader) error {
+	environment, err := readYAML(r)
+	if err != nil {
+		return e.handleError(e.fail(err))
+	}
+	return e.Parse(environment)
+}
+
+// ParseYAML parses variables definitions from r, a flat YAML document,
+// into the default set. See [EnvSet.ParseYAML] for the format.
+func ParseYAML(r io.Reader) error {
+	return Environment.ParseYAML(r)
+}
+
+// readYAML reads the key/value pairs from the flat YAML document r.
+func readYAML(r io.Reader) ([]string, error) {
+	var environment []string
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || trimmed[0] == '#' || trimmed == "---" {
+			continue
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			return nil, fmt.Errorf("line %d: nested values are not supported", n)
+		}
+		if strings.HasPrefix(trimmed, "- ") || trimmed == "-" {
+			return nil, fmt.Errorf("line %d: lists are not supported", n)
+		}
+		key, value, ok := strings.Cut(trimmed, ":")
+		if !ok {
+			return nil, fmt.Errorf("line %d: missing : in %q", n, trimmed)
+		}
+		key = strings.TrimSpace(key)
+		if key == "" {
+			return nil, fmt.Errorf("line %d: missing key", n)
+		}
+		value, err := yamlValue(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", n, err)
+		}
+		environment = append(environment, key+"="+value)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return environment, nil
+}
+
+// yamlValue returns the scalar s, as found on the right of : in a flat YAML
+// document, without quotes or trailing comment.
+func yamlValue(s string) (string, error) {
+	if s == "" {
+		// a null scalar, nested values are reported by readYAML
+		return "", nil
+	}
+	switch s[0] {
+	case '[', '{':
+		return "", fmt.Errorf("flow collections are not supported")
+	case '|', '>':
+		return "", fmt.Errorf("block scalars are not supported")
+	case '&', '*', '!':
+		return "", fmt.Errorf("anchors, aliases and tags are not supported")
+	case '"':
+		end := 1
+		for ; end < len(s) && s[end] != '"'; end++ {
+			if s[end] == '\\' {
+				end++
+			}
+		}
+		if end >= len(s) {
+			return "", fmt.Errorf("unterminated quoted value %s", s)
+		}
+		if rest := strings.TrimSpace(s[end+1:]); rest != "" && rest[0] != '#' {
+			return "", fmt.Errorf("unexpected %q after quoted value", rest)
+		}
+		return strconv.Unquote(s[:end+1])
+	case '\'':
+		var b strings.Builder
+		for i := 1; i < len(s); i++ {
+			if s[i] != '\'' {
+				b.WriteByte(s[i])
+				continue
+			}
+			if i+1 < len(s) && s[i+1] == '\'' {
+				// '' stands for a single quote
+				b.WriteByte('\'')
+				i++
+				continue
+			}
+			if rest := strings.TrimSpace(s[i+1:]); rest != "" && rest[0] != '#' {
+				return "", fmt.Errorf("unexpected %q after quoted value", rest)
+			}
+			return b.String(), nil
+		}
+		return "", fmt.Errorf("unterminated quoted value %s", s)
+	}
+	return stripComment(s), nil
+}
@@ -0,0 +1,34 @@
+// Copyright 2024, Edoardo Putti
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package env
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestReadYAML(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"A: 1 # comment", []string{"A=1"}},
+		{"A: # comment", []string{"A="}},
+		{"A:", []string{"A="}},
+		{"A: a#b", []string{"A=a#b"}},
+		{`A: "hello # world" # comment`, []string{"A=hello # world"}},
+		{"A: 'it''s'", []string{"A=it's"}},
+	}
+	for _, tt := range tests {
+		got, err := readYAML(strings.NewReader(tt.in))
+		if err != nil || !slices.Equal(got, tt.want) {
+			t.Errorf("readYAML(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+	}
+	if _, err := readYAML(strings.NewReader("A:\n  B: 1\n")); err == nil {
+		t.Error("readYAML accepted a nested mapping")
+	}
+}